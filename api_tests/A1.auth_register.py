@@ -16,7 +16,6 @@ payload = {
     "name": "Test User Automator",
     "email": email,
     "password": "password123",
-    "role": "user",
 }
 
 response = send_and_print(
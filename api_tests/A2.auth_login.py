@@ -25,6 +25,7 @@ if response.status_code == 200:
     # Save tokens to secrets.json for subsequent requests
     save_config("accessToken", data['tokens']['access']['token'])
     save_config("refreshToken", data['tokens']['refresh']['token'])
+    save_config("user_id", data['user']['id'])
     print(">>> Login successful. Access and Refresh tokens saved.")
 else:
     print(">>> Login Failed.")
\ No newline at end of file
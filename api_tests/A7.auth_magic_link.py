@@ -0,0 +1,20 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL
+
+print("--- REQUEST MAGIC LINK ---")
+
+url = f"{BASE_URL}/auth/magic-link"
+
+# Always 204, whether or not the account exists (429 after too many requests for one address)
+payload = {
+    "email": "admin@example.com"
+}
+
+response = send_and_print(
+    url=url,
+    method="POST",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
\ No newline at end of file
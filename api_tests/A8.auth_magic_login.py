@@ -0,0 +1,30 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, save_config
+
+print("--- SIGN IN WITH MAGIC LINK ---")
+
+mock_token = "PUT_VALID_TOKEN_HERE_FROM_LOGS"
+
+# The link lives outside /v1; Accept: application/json exchanges the token instead of rendering the page
+url = f"{BASE_URL[:-3]}/auth/magic?token={mock_token}"
+headers = {
+    "Accept": "application/json"
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="GET",
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+
+if response.status_code == 200:
+    data = response.json()
+    save_config("accessToken", data['tokens']['access']['token'])
+    save_config("refreshToken", data['tokens']['refresh']['token'])
+    save_config("user_id", data['user']['id'])
+    print(">>> Magic link accepted. Access and Refresh tokens saved.")
+else:
+    print(">>> Magic link rejected (already used, expired or invalid).")
\ No newline at end of file
@@ -0,0 +1,38 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config, save_config
+
+print("--- CREATE API KEY ---")
+
+token = load_config("accessToken")
+user_id = load_config("user_id")
+
+if not token or not user_id:
+    print("Error: No access token / user ID. Run A2.auth_login.py first.")
+    sys.exit(1)
+
+url = f"{BASE_URL}/users/{user_id}/api-keys"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+payload = {
+    "name": "Python test script",
+    "scopes": ["users:read", "apikeys:manage"],
+    "expiresInDays": 1
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="POST",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+
+if response.status_code == 201:
+    data = response.json()
+    # The plain key is only returned once, keep it for C2/C3
+    save_config("apiKey", data['key'])
+    save_config("apiKey_id", data['apiKey']['id'])
+    print(f">>> API key created ({data['apiKey']['prefix']}...). Key saved to secrets.json for testing C2/C3.")
\ No newline at end of file
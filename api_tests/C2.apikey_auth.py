@@ -0,0 +1,36 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- AUTHENTICATE WITH API KEY ---")
+
+api_key = load_config("apiKey")
+user_id = load_config("user_id")
+
+if not api_key:
+    print("Error: No API key. Run C1.apikey_create.py first.")
+    sys.exit(1)
+
+headers = {
+    "Authorization": f"ApiKey {api_key}"
+}
+
+# 1. Allowed: key holds users:read
+response = send_and_print(
+    url=f"{BASE_URL}/users/{user_id}",
+    headers=headers,
+    method="GET",
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+print(f">>> GET /users/{{id}} with users:read scope -> {response.status_code} (expected 200)")
+
+# 2. Denied: key does not hold users:write
+response = send_and_print(
+    url=f"{BASE_URL}/users/{user_id}",
+    headers=headers,
+    method="PATCH",
+    body={"name": "Should Not Change"},
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}_denied.json"
+)
+print(f">>> PATCH /users/{{id}} without users:write scope -> {response.status_code} (expected 403)")
\ No newline at end of file
@@ -0,0 +1,26 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- REVOKE API KEY ---")
+
+token = load_config("accessToken")
+user_id = load_config("user_id")
+key_id = load_config("apiKey_id")
+
+if not token or not key_id:
+    print("Error: No access token / API key ID. Run A2.auth_login.py and C1.apikey_create.py first.")
+    sys.exit(1)
+
+url = f"{BASE_URL}/users/{user_id}/api-keys/{key_id}"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="DELETE",
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
\ No newline at end of file
@@ -0,0 +1,27 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- UPDATE OWN PROFILE ---")
+
+token = load_config("accessToken")
+if not token:
+    print("Error: No access token. Run A2.auth_login.py first.")
+    sys.exit(1)
+
+url = f"{BASE_URL}/me"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+payload = {
+    "name": "Self Updated via Python"
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="PATCH",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
\ No newline at end of file
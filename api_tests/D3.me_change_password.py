@@ -0,0 +1,36 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config, save_config
+
+print("--- CHANGE OWN PASSWORD ---")
+
+token = load_config("accessToken")
+if not token:
+    print("Error: No access token. Run A2.auth_login.py first.")
+    sys.exit(1)
+
+url = f"{BASE_URL}/me/password"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+# Keep the same password so A2.auth_login.py keeps working
+payload = {
+    "currentPassword": "password123",
+    "newPassword": "password123"
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="POST",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+
+if response.status_code == 200:
+    data = response.json()
+    # Every other session was revoked, continue with the fresh pair
+    save_config("accessToken", data['tokens']['access']['token'])
+    save_config("refreshToken", data['tokens']['refresh']['token'])
+    print(">>> Password changed. New tokens saved.")
\ No newline at end of file
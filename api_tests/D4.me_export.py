@@ -0,0 +1,24 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- EXPORT OWN DATA ---")
+
+token = load_config("accessToken")
+if not token:
+    print("Error: No access token. Run A2.auth_login.py first.")
+    sys.exit(1)
+
+# Use ?format=zip for a ZIP archive instead of a single JSON document
+url = f"{BASE_URL}/me/export?format=json"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="GET",
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
\ No newline at end of file
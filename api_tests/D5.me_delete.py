@@ -0,0 +1,41 @@
+import sys
+import os
+import time
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL
+
+print("--- DELETE OWN ACCOUNT ---")
+
+# Use a throwaway account so the saved admin session stays usable
+timestamp = int(time.time())
+reg_payload = {
+    "name": "Account To Delete",
+    "email": f"delete_me_{timestamp}@test.com",
+    "password": "password123"
+}
+reg_response = send_and_print(f"{BASE_URL}/auth/register", method="POST", body=reg_payload, output_file="temp_delete_user.json")
+
+if reg_response.status_code != 201:
+    print("Error: Failed to register the throwaway user.")
+    sys.exit(1)
+
+token = reg_response.json()['tokens']['access']['token']
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+payload = {
+    "password": "password123"
+}
+
+response = send_and_print(
+    url=f"{BASE_URL}/me",
+    headers=headers,
+    method="DELETE",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+
+if response.status_code == 202:
+    print(f">>> Deletion scheduled for {response.json()['deletionScheduledAt']}.")
+elif response.status_code == 204:
+    print(">>> Account deleted immediately (no grace period configured).")
\ No newline at end of file
@@ -0,0 +1,29 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- UPDATE SECURITY EMAIL PREFERENCES ---")
+
+token = load_config("accessToken")
+if not token:
+    print("Error: No access token. Run A2.auth_login.py first.")
+    sys.exit(1)
+
+url = f"{BASE_URL}/me/notifications"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+# Omitted fields keep their current value
+payload = {
+    "newLogin": False,
+    "adminChanges": True
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="PATCH",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
\ No newline at end of file
@@ -0,0 +1,26 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL
+
+print("--- ACCEPT INVITATION ---")
+
+# Token from the invitation email link (/accept-invite?token=...)
+mock_token = "PUT_VALID_TOKEN_HERE_FROM_EMAIL"
+
+url = f"{BASE_URL}/auth/accept-invite?token={mock_token}"
+
+payload = {
+    "name": "Invited User",
+    "password": "password123"
+}
+
+response = send_and_print(
+    url=url,
+    method="POST",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+
+if response.status_code == 201:
+    print(f">>> Account created for {response.json()['user']['email']}.")
\ No newline at end of file
@@ -0,0 +1,26 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- LIST FAILED EMAILS (ADMIN) ---")
+
+token = load_config("accessToken")
+
+if not token:
+    print("Error: No access token. Run A2.auth_login.py with an admin account first.")
+    sys.exit(1)
+
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+
+response = send_and_print(
+    url=f"{BASE_URL}/admin/emails?status=failed&limit=10",
+    headers=headers,
+    method="GET",
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+
+if response.status_code == 200:
+    print(f">>> {response.json()['totalResults']} failed email(s). Retry one with POST /v1/admin/emails/{{id}}/retry.")
\ No newline at end of file
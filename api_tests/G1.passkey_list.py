@@ -0,0 +1,28 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config, save_config
+
+print("--- LIST MY PASSKEYS ---")
+
+token = load_config("accessToken")
+if not token:
+    print("Error: No access token. Run A2.auth_login.py first.")
+    sys.exit(1)
+
+# Passkeys are registered from the Profile page, the ceremony needs a browser authenticator
+url = f"{BASE_URL}/me/passkeys"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="GET",
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
+
+if response.status_code == 200 and response.json():
+    save_config("passkey_id", response.json()[0]['id'])
+    print(">>> First passkey ID saved.")
\ No newline at end of file
@@ -0,0 +1,29 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- REQUIRE A PASSKEY AT SIGN IN ---")
+
+token = load_config("accessToken")
+if not token:
+    print("Error: No access token. Run A2.auth_login.py first.")
+    sys.exit(1)
+
+# Fails with 400 until at least one passkey is registered.
+# Afterwards A2.auth_login.py answers with passkeyRequired and a loginToken instead of tokens.
+url = f"{BASE_URL}/me/passkeys/required"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+payload = {
+    "required": True
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="PUT",
+    body=payload,
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
\ No newline at end of file
@@ -0,0 +1,25 @@
+import sys
+import os
+sys.path.append(os.path.abspath(os.path.dirname(__file__)))
+from utils import send_and_print, BASE_URL, load_config
+
+print("--- REMOVE A PASSKEY ---")
+
+token = load_config("accessToken")
+passkey_id = load_config("passkey_id")
+
+if not token or not passkey_id:
+    print("Error: No access token / passkey ID. Run A2.auth_login.py and G1.passkey_list.py first.")
+    sys.exit(1)
+
+url = f"{BASE_URL}/me/passkeys/{passkey_id}"
+headers = {
+    "Authorization": f"Bearer {token}"
+}
+
+response = send_and_print(
+    url=url,
+    headers=headers,
+    method="DELETE",
+    output_file=f"{os.path.splitext(os.path.basename(__file__))[0]}.json"
+)
\ No newline at end of file
@@ -51,5 +51,8 @@ if response.status_code == 201:
     else:
         print(f"\n{Colors.FAIL}[FAIL] Security Vulnerability! User was created with role '{user_role}'.{Colors.ENDC}")
 
+elif response.status_code == 400 and response.json().get('code') == 'unknown_field':
+    print(f"\n{Colors.OKGREEN}[PASS] Security check passed! Server rejected the unknown 'role' field.{Colors.ENDC}")
+
 else:
     print(f"\n{Colors.FAIL}[FAIL] Registration failed with status {response.status_code}. Could not verify security.{Colors.ENDC}")
\ No newline at end of file
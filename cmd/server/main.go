@@ -1,8 +1,13 @@
 package main
 
 import (
-	"log"
+	"context"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"starter-kit-fullstack-gonethttp-template/config"
@@ -12,7 +17,17 @@ import (
 	"starter-kit-fullstack-gonethttp-template/internal/repository"
 	"starter-kit-fullstack-gonethttp-template/internal/routes"
 	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/assets"
+	"starter-kit-fullstack-gonethttp-template/pkg/flash"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/logger"
+	"starter-kit-fullstack-gonethttp-template/pkg/mailer"
+	"starter-kit-fullstack-gonethttp-template/pkg/telemetry"
+	"starter-kit-fullstack-gonethttp-template/pkg/validation"
 	"starter-kit-fullstack-gonethttp-template/pkg/view"
+	"starter-kit-fullstack-gonethttp-template/web"
+
+	"github.com/go-playground/validator/v10"
 )
 
 // @title Starter Kit Fullstack Go Native
@@ -30,45 +45,143 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+// @description Use "Bearer <access token>" or "ApiKey <personal access token>"
 func main() {
 	// 1. Load Configuration
 	cfg := config.LoadConfig()
 
-	// 2. Initialize Template Engine
-	view.Init(cfg)
+	if err := logger.InitLogger(logger.Options{
+		Format:     cfg.Log.Format,
+		Level:      cfg.Log.Level,
+		File:       cfg.Log.File,
+		MaxSizeMB:  cfg.Log.MaxSizeMB,
+		MaxBackups: cfg.Log.MaxBackups,
+		MaxAgeDays: cfg.Log.MaxAgeDays,
+	}); err != nil {
+		logger.Fatal("Logger setup failed", "error", err)
+	}
+
+	// Tracing
+	shutdownTracing, err := telemetry.Init(context.Background(), telemetry.Options{
+		ServiceName: cfg.App.Name,
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("Tracing setup failed", "error", err)
+	}
+
+	// 2. Initialize Template Engine and Translations
+	webFS := web.FS(cfg.App.Env)
+	if err := i18n.Load(mustSub(webFS, "locales"), cfg.App.Locale); err != nil {
+		logger.Fatal("Failed to load translations", "error", err)
+	}
+	static, err := assets.New(mustSub(webFS, "static"), "/assets/", cfg.App.Env == "development")
+	if err != nil {
+		logger.Fatal("Failed to load static files", "error", err)
+	}
+	if err := view.Init(cfg, mustSub(webFS, "templates"), static); err != nil {
+		logger.Fatal("Failed to parse templates", "error", err)
+	}
+	flash.Init(cfg.JWT.Secret)
 
 	// 3. Connect Database
 	config.ConnectDB(cfg)
 
 	// 4. Auto Migration
-	log.Println("Running Database Migrations...")
-	err := config.DB.AutoMigrate(&models.User{}, &models.Token{})
+	slog.Info("Running database migrations")
+	err = config.DB.AutoMigrate(&models.User{}, &models.Token{}, &models.APIKey{}, &models.Invitation{}, &models.OutboxEmail{}, &models.Device{}, &models.WebAuthnCredential{}, &models.WebAuthnSession{}, &models.AuditEntry{})
 	if err != nil {
-		log.Fatalf("Migration failed: %v", err)
+		logger.Fatal("Migration failed", "error", err)
 	}
 
 	// 5. Setup Dependency Injection
 	userRepo := repository.NewUserRepository(config.DB)
 	tokenRepo := repository.NewTokenRepository(config.DB)
+	apiKeyRepo := repository.NewAPIKeyRepository(config.DB)
+	invitationRepo := repository.NewInvitationRepository(config.DB)
+	outboxRepo := repository.NewOutboxRepository(config.DB)
+	deviceRepo := repository.NewDeviceRepository(config.DB)
+	webAuthnRepo := repository.NewWebAuthnRepository(config.DB)
+	auditRepo := repository.NewAuditRepository(config.DB)
+
+	// unique_email rejects addresses that already have an account, so registration
+	// reports it next to the field instead of as a 409
+	if err := validation.Register("unique_email", func(ctx context.Context, fl validator.FieldLevel) bool {
+		exists, err := userRepo.ExistsByEmail(ctx, fl.Field().String())
+		return err != nil || !exists // a lookup failure is left to the service
+	}); err != nil {
+		logger.Fatal("Failed to register validation rule", "error", err)
+	}
+	// locale accepts the languages with a catalog, or "" for the browser's language
+	if err := validation.Register("locale", func(ctx context.Context, fl validator.FieldLevel) bool {
+		return fl.Field().String() == "" || i18n.Supported(fl.Field().String())
+	}); err != nil {
+		logger.Fatal("Failed to register validation rule", "error", err)
+	}
+
+	// Services publish account events here; security notices and the audit log subscribe to them
+	events := services.NewEventBus()
+	services.SubscribeAuditLog(events, auditRepo)
 
 	tokenService := services.NewTokenService(tokenRepo, cfg)
-	emailService := services.NewEmailService(cfg)
-	userService := services.NewUserService(userRepo)
-	authService := services.NewAuthService(userRepo, tokenRepo, tokenService, emailService, cfg)
+	emailRenderer := mailer.NewRenderer(mustSub(webFS, "templates/emails"), cfg.App.Locale)
+	emailTransport, inbox := newEmailTransport(cfg)
+	outboxService := services.NewOutboxService(outboxRepo, emailTransport, cfg)
+	emailService := services.NewEmailService(cfg, emailRenderer, outboxService)
+	accountService := services.NewAccountService(userRepo, tokenRepo, apiKeyRepo, deviceRepo, webAuthnRepo, invitationRepo, auditRepo, emailService, cfg)
+	userService := services.NewUserService(userRepo, tokenRepo, accountService, events)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo)
+	authService := services.NewAuthService(userRepo, tokenRepo, tokenService, emailService, events, cfg)
+	invitationService := services.NewInvitationService(invitationRepo, userRepo, tokenService, emailService, cfg)
+	notificationService := services.NewNotificationService(events, userRepo, tokenRepo, apiKeyRepo, deviceRepo, webAuthnRepo, tokenService, emailService, cfg)
+	statsService := services.NewStatsService(userRepo, tokenRepo, deviceRepo)
+	webAuthnService, err := services.NewWebAuthnService(webAuthnRepo, userRepo, tokenRepo, tokenService, events, cfg)
+	if err != nil {
+		logger.Fatal("Passkey setup failed (check WEBAUTHN_RP_ID / WEBAUTHN_ORIGINS)", "error", err)
+	}
 
 	handlers := routes.Handlers{
-		APIAuth: apiHandlers.NewAuthHandler(authService),
-		APIUser: apiHandlers.NewUserHandler(userService),
-		WebAuth: webHandlers.NewAuthHandler(),
-		WebUser: webHandlers.NewUserHandler(),
-		WebDash: webHandlers.NewDashboardHandler(),
+		APIAuth:    apiHandlers.NewAuthHandler(authService, cfg),
+		APIUser:    apiHandlers.NewUserHandler(userService),
+		APIAPIKey:  apiHandlers.NewAPIKeyHandler(apiKeyService),
+		APIMe:      apiHandlers.NewProfileHandler(userService, authService, accountService, cfg),
+		APIInvite:  apiHandlers.NewInvitationHandler(invitationService, cfg),
+		APIOutbox:  apiHandlers.NewOutboxHandler(outboxService),
+		APINotify:  apiHandlers.NewNotificationHandler(notificationService, cfg),
+		APIPasskey: apiHandlers.NewWebAuthnHandler(webAuthnService, cfg),
+		APIStats:   apiHandlers.NewStatsHandler(statsService),
+		WebAuth:    webHandlers.NewAuthHandler(authService, cfg),
+		WebUser:    webHandlers.NewUserHandler(userService),
+		WebDash:    webHandlers.NewDashboardHandler(statsService),
+		WebAPIKey:  webHandlers.NewAPIKeyHandler(),
+		WebMe:      webHandlers.NewProfileHandler(),
+		WebInvite:  webHandlers.NewInvitationHandler(invitationService),
+		WebEmails:  webHandlers.NewEmailPreviewHandler(emailRenderer, cfg),
+		WebOutbox:  webHandlers.NewOutboxHandler(),
+		WebSecure:  webHandlers.NewSecureAccountHandler(notificationService),
+	}
+	if inbox != nil {
+		handlers.WebInbox = webHandlers.NewInboxHandler(inbox)
 	}
 
 	// 6. Setup Router
-	// Pass userService here for Middleware Roles
-	router := routes.RegisterRoutes(cfg, handlers, userService)
+	// Pass userService here for Middleware Roles, apiKeyService for API key authentication,
+	// authService to renew the session cookies
+	router := routes.RegisterRoutes(cfg, handlers, userService, apiKeyService, authService, static)
 
-	// 7. Start Server
+	// 7. Background Jobs, the outbox stops on shutdown
+	go purgeDeletedAccounts(accountService)
+	outboxCtx, stopOutbox := context.WithCancel(context.Background())
+	outboxDone := make(chan struct{})
+	go func() {
+		outboxService.Run(outboxCtx)
+		close(outboxDone)
+	}()
+
+	// 8. Start Server
 	srv := &http.Server{
 		Addr:         ":" + cfg.App.Port,
 		Handler:      router,
@@ -77,10 +190,92 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	log.Printf("Server starting on port %s", cfg.App.Port)
-	log.Printf("Swagger Docs available at %s/swagger/index.html", cfg.App.URL)
+	slog.Info("Server starting", "port", cfg.App.Port)
+	slog.Info("Swagger docs available", "url", cfg.App.URL+"/swagger/index.html")
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Server failed to start", "error", err)
+		}
+	}()
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	// 9. Graceful Shutdown, finishing in-flight requests and emails and flushing pending spans
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	slog.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server shutdown failed", "error", err)
+	}
+	// After the requests, so the emails they queued are sent or left for the next start
+	stopOutbox()
+	select {
+	case <-outboxDone:
+	case <-shutdownCtx.Done():
+		slog.Error("Email outbox shutdown timed out")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Tracing shutdown failed", "error", err)
+	}
+}
+
+// purgeDeletedAccounts permanently removes self-deleted accounts once their grace period is over
+func purgeDeletedAccounts(accountService services.AccountService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		purged, err := accountService.PurgeScheduledDeletions(context.Background())
+		if err != nil {
+			slog.Error("Account purge failed", "error", err)
+		}
+		if purged > 0 {
+			slog.Info("Purged deleted accounts", "count", purged)
+		}
+	}
+}
+
+// newEmailTransport picks the email transport from EMAIL_TRANSPORT.
+// The memory transport is also returned on its own so the dev inbox can read it.
+func newEmailTransport(cfg *config.Config) (mailer.EmailTransport, *mailer.MemoryTransport) {
+	switch cfg.Email.Transport {
+	case "smtp":
+		return &mailer.SMTPTransport{
+			Host:               cfg.SMTP.Host,
+			Port:               cfg.SMTP.Port,
+			Username:           cfg.SMTP.Username,
+			Password:           cfg.SMTP.Password,
+			Encryption:         cfg.SMTP.Encryption,
+			InsecureSkipVerify: cfg.SMTP.InsecureSkipVerify,
+		}, nil
+	case "file":
+		slog.Info("Emails are written to disk", "dir", cfg.Email.FileDir)
+		return &mailer.FileTransport{Dir: cfg.Email.FileDir}, nil
+	case "memory":
+		// The inbox has no sign-in and shows every reset, verification and sign-in link
+		if cfg.App.Env != "development" {
+			logger.Fatal("EMAIL_TRANSPORT=memory is only allowed with APP_ENV=development", "env", cfg.App.Env)
+		}
+		inbox := mailer.NewMemoryTransport(200)
+		slog.Info("Emails are captured in memory", "inbox", cfg.App.URL+"/dev/inbox")
+		return inbox, inbox
+	case "log":
+		return mailer.LogTransport{ShowBody: cfg.App.Env == "development"}, nil
+	default:
+		logger.Fatal("Unknown EMAIL_TRANSPORT (use smtp, file, memory or log)", "transport", cfg.Email.Transport)
+		return nil, nil
+	}
+}
+
+// mustSub narrows the web files to one directory. fs.Sub only fails on invalid paths,
+// which the constant names used here are not.
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
 	}
+	return sub
 }
\ No newline at end of file
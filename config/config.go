@@ -1,19 +1,24 @@
 package config
 
 import (
-	"log"
+	"log/slog"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/logger"
 )
 
 type Config struct {
 	App struct {
-		Name string
-		Env  string
-		Port string
-		URL  string
+		Name   string
+		Env    string
+		Port   string
+		URL    string
+		Locale string // Default locale for API messages, pages and emails
 	}
 	DB struct {
 		Driver   string // sqlite or postgres
@@ -30,13 +35,49 @@ type Config struct {
 		RefreshExpirationDays    int
 		ResetPasswordExpiration  int // Minutes
 		VerifyEmailExpiration    int // Minutes
+		InviteExpiration         int // Hours
+		SecureAccountExpiration  int // Hours, "this wasn't me" links in security emails
+		MagicLinkExpiration      int // Minutes
 	}
 	SMTP struct {
-		Host     string
-		Port     int
-		Username string
-		Password string
-		From     string
+		Host               string
+		Port               int
+		Username           string
+		Password           string
+		From               string
+		Encryption         string // none, starttls or tls (implicit TLS)
+		InsecureSkipVerify bool   // Skip certificate verification (self-signed dev servers only)
+	}
+	Email struct {
+		Transport        string // smtp, file, memory or log
+		FileDir          string // Where the file transport writes .eml files
+		Workers          int // Concurrent outbox senders
+		MaxAttempts      int // Attempts before an email is dead-lettered
+		RetryBaseSeconds int // First retry delay, doubled after every failure
+	}
+	Auth struct {
+		MagicLinkPerHour int // Sign-in links one email address may request per hour
+	}
+	WebAuthn struct {
+		RPID    string   // Relying party ID, the domain passkeys are bound to
+		Origins []string // Origins allowed to run the ceremonies
+	}
+	Account struct {
+		DeletionGraceDays int // Days before a self-deleted account is purged (0 = immediately)
+	}
+	Log struct {
+		Format     string // text or json
+		Level      string // debug, info, warn or error
+		File       string // Optional file, written in addition to stdout and rotated by size
+		MaxSizeMB  int
+		MaxBackups int
+		MaxAgeDays int
+	}
+	Tracing struct {
+		Enabled     bool    // Export spans to an OpenTelemetry collector
+		Endpoint    string  // OTLP/HTTP collector address, host:port
+		Insecure    bool    // Talk plain HTTP to the collector
+		SampleRatio float64 // Share of new traces to record (0-1), incoming sampled traces are always kept
 	}
 }
 
@@ -44,7 +85,7 @@ type Config struct {
 func LoadConfig() *Config {
 	// Load .env file if present
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+		slog.Info("No .env file found, using system environment variables")
 	}
 
 	cfg := &Config{}
@@ -54,6 +95,7 @@ func LoadConfig() *Config {
 	cfg.App.Env = getEnv("APP_ENV", "development")
 	cfg.App.Port = getEnv("PORT", "8080")
 	cfg.App.URL = getEnv("APP_URL", "http://localhost:8080")
+	cfg.App.Locale = getEnv("APP_LOCALE", "en")
 
 	// Database
 	cfg.DB.Driver = getEnv("DB_DRIVER", "sqlite")
@@ -70,6 +112,9 @@ func LoadConfig() *Config {
 	cfg.JWT.RefreshExpirationDays, _ = strconv.Atoi(getEnv("JWT_REFRESH_EXPIRATION_DAYS", "30"))
 	cfg.JWT.ResetPasswordExpiration, _ = strconv.Atoi(getEnv("JWT_RESET_PASSWORD_EXPIRATION_MINUTES", "10"))
 	cfg.JWT.VerifyEmailExpiration, _ = strconv.Atoi(getEnv("JWT_VERIFY_EMAIL_EXPIRATION_MINUTES", "10"))
+	cfg.JWT.InviteExpiration, _ = strconv.Atoi(getEnv("JWT_INVITE_EXPIRATION_HOURS", "72"))
+	cfg.JWT.SecureAccountExpiration, _ = strconv.Atoi(getEnv("JWT_SECURE_ACCOUNT_EXPIRATION_HOURS", "168"))
+	cfg.JWT.MagicLinkExpiration, _ = strconv.Atoi(getEnv("JWT_MAGIC_LINK_EXPIRATION_MINUTES", "15"))
 
 	// SMTP
 	cfg.SMTP.Host = getEnv("SMTP_HOST", "")
@@ -77,6 +122,57 @@ func LoadConfig() *Config {
 	cfg.SMTP.Username = getEnv("SMTP_USERNAME", "")
 	cfg.SMTP.Password = getEnv("SMTP_PASSWORD", "")
 	cfg.SMTP.From = getEnv("EMAIL_FROM", "noreply@example.com")
+	cfg.SMTP.InsecureSkipVerify, _ = strconv.ParseBool(getEnv("SMTP_TLS_INSECURE_SKIP_VERIFY", "false"))
+	defaultEncryption := "starttls"
+	if cfg.SMTP.Port == 465 {
+		defaultEncryption = "tls"
+	}
+	cfg.SMTP.Encryption = getEnv("SMTP_ENCRYPTION", defaultEncryption)
+
+	// Email Transport (defaults to console logging in development when no SMTP host is set)
+	defaultTransport := "smtp"
+	if cfg.App.Env == "development" && cfg.SMTP.Host == "" {
+		defaultTransport = "log"
+	}
+	cfg.Email.Transport = getEnv("EMAIL_TRANSPORT", defaultTransport)
+	cfg.Email.FileDir = getEnv("EMAIL_FILE_DIR", "storage/emails")
+
+	// Email Outbox
+	cfg.Email.Workers, _ = strconv.Atoi(getEnv("EMAIL_WORKERS", "2"))
+	cfg.Email.MaxAttempts, _ = strconv.Atoi(getEnv("EMAIL_MAX_ATTEMPTS", "5"))
+	cfg.Email.RetryBaseSeconds, _ = strconv.Atoi(getEnv("EMAIL_RETRY_BASE_SECONDS", "30"))
+
+	// Auth
+	cfg.Auth.MagicLinkPerHour, _ = strconv.Atoi(getEnv("AUTH_MAGIC_LINK_PER_HOUR", "5"))
+
+	// WebAuthn (defaults to the host and origin of APP_URL)
+	appURL, err := url.Parse(cfg.App.URL)
+	if err != nil {
+		logger.Fatal("Invalid APP_URL", "error", err)
+	}
+	cfg.WebAuthn.RPID = getEnv("WEBAUTHN_RP_ID", appURL.Hostname())
+	cfg.WebAuthn.Origins = strings.Split(getEnv("WEBAUTHN_ORIGINS", strings.TrimSuffix(cfg.App.URL, "/")), ",")
+
+	// Account
+	cfg.Account.DeletionGraceDays, _ = strconv.Atoi(getEnv("ACCOUNT_DELETION_GRACE_DAYS", "7"))
+
+	// Logging (readable text while developing, JSON for log shippers otherwise)
+	defaultLogFormat, defaultLogLevel := "json", "info"
+	if cfg.App.Env == "development" {
+		defaultLogFormat, defaultLogLevel = "text", "debug"
+	}
+	cfg.Log.Format = getEnv("LOG_FORMAT", defaultLogFormat)
+	cfg.Log.Level = getEnv("LOG_LEVEL", defaultLogLevel)
+	cfg.Log.File = getEnv("LOG_FILE", "")
+	cfg.Log.MaxSizeMB, _ = strconv.Atoi(getEnv("LOG_FILE_MAX_SIZE_MB", "100"))
+	cfg.Log.MaxBackups, _ = strconv.Atoi(getEnv("LOG_FILE_MAX_BACKUPS", "5"))
+	cfg.Log.MaxAgeDays, _ = strconv.Atoi(getEnv("LOG_FILE_MAX_AGE_DAYS", "30"))
+
+	// Tracing
+	cfg.Tracing.Enabled, _ = strconv.ParseBool(getEnv("TRACING_ENABLED", "false"))
+	cfg.Tracing.Endpoint = getEnv("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	cfg.Tracing.Insecure, _ = strconv.ParseBool(getEnv("TRACING_OTLP_INSECURE", "true"))
+	cfg.Tracing.SampleRatio, _ = strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1"), 64)
 
 	return cfg
 }
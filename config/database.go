@@ -2,12 +2,15 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/logger"
+	"starter-kit-fullstack-gonethttp-template/pkg/telemetry"
 )
 
 var DB *gorm.DB
@@ -21,28 +24,32 @@ func ConnectDB(cfg *Config) {
 	if cfg.DB.Driver == "sqlite" {
 		dsn = cfg.DB.Name // For SQLite, DB_NAME is the file path
 		dialector = sqlite.Open(dsn)
-		log.Printf("Connecting to SQLite: %s", dsn)
+		slog.Info("Connecting to SQLite", "file", dsn)
 	} else {
 		// Postgres DSN
 		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
 			cfg.DB.Host, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.Port, cfg.DB.SSLMode)
 		dialector = postgres.Open(dsn)
-		log.Printf("Connecting to Postgres at %s", cfg.DB.Host)
+		slog.Info("Connecting to Postgres", "host", cfg.DB.Host)
 	}
 
-	// Configure GORM Logger
-	logLevel := logger.Error
+	// Configure GORM Logger (every query is logged at debug level in development)
+	logLevel := gormlogger.Warn
 	if cfg.App.Env == "development" {
-		logLevel = logger.Info
+		logLevel = gormlogger.Info
 	}
 
 	DB, err = gorm.Open(dialector, &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger: logger.NewGormLogger(logLevel),
 	})
 
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+
+	if err := DB.Use(telemetry.GormTracing{}); err != nil {
+		logger.Fatal("Failed to register database tracing", "error", err)
 	}
 
-	log.Println("Database connection established successfully")
+	slog.Info("Database connection established successfully")
 }
\ No newline at end of file
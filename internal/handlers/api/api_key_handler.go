@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	service services.APIKeyService
+}
+
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a personal access token for machine clients, only for the signed in user's own account. The plain key is only returned once.
+// @Description Called with an API key, the new key can only get scopes the calling key holds.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body services.CreateAPIKeyRequest true "Create API Key Request"
+// @Success 201 {object} object{apiKey=models.APIKey,key=string}
+// @Failure 400 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Router /v1/users/{id}/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid User ID")
+		return
+	}
+
+	var req services.CreateAPIKeyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// A key can't hand out more than it holds, or apikeys:manage would grant everything
+	if caller, ok := middleware.CurrentAPIKey(r); ok {
+		for _, scope := range req.Scopes {
+			if !caller.HasScope(scope) {
+				response.Error(w, r, http.StatusForbidden, i18n.T(r.Context(), "Forbidden: API key lacks scope {scope}", "scope", scope))
+				return
+			}
+		}
+	}
+
+	key, rawKey, err := h.service.CreateKey(r.Context(), userID, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, map[string]interface{}{
+		"apiKey": key,
+		"key":    rawKey,
+	})
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description List the API keys of a user (secrets are never returned)
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {array} models.APIKey
+// @Failure 400 {object} response.Problem
+// @Router /v1/users/{id}/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid User ID")
+		return
+	}
+
+	keys, err := h.service.ListKeys(r.Context(), userID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, keys)
+}
+
+// DeleteAPIKey godoc
+// @Summary Revoke an API key
+// @Description Permanently revoke an API key
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param keyId path string true "API Key ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} response.Problem
+// @Router /v1/users/{id}/api-keys/{keyId} [delete]
+func (h *APIKeyHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid User ID")
+		return
+	}
+
+	keyID, err := uuid.Parse(r.PathValue("keyId"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid API Key ID")
+		return
+	}
+
+	if err := h.service.RevokeKey(r.Context(), userID, keyID); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// stubAPIKeys records the keys it is asked to create, the other methods are not used
+type stubAPIKeys struct {
+	services.APIKeyService
+	created []services.CreateAPIKeyRequest
+}
+
+func (s *stubAPIKeys) CreateKey(ctx context.Context, userID uuid.UUID, req services.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	s.created = append(s.created, req)
+	return &models.APIKey{UserID: userID, Name: req.Name, Scopes: req.Scopes}, "sk_test", nil
+}
+
+func TestCreateAPIKeyScopes(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		caller     *models.APIKey // nil for a user's token or session
+		scopes     string
+		wantStatus int
+	}{
+		{"manage-only key can't mint users:write", &models.APIKey{Scopes: []string{models.ScopeAPIKeysManage}}, `["users:write"]`, http.StatusForbidden},
+		{"key can't add a scope to ones it holds", &models.APIKey{Scopes: []string{models.ScopeAPIKeysManage, models.ScopeUsersRead}}, `["users:read","users:write"]`, http.StatusForbidden},
+		{"key can mint its own scopes", &models.APIKey{Scopes: []string{models.ScopeAPIKeysManage, models.ScopeUsersRead}}, `["users:read"]`, http.StatusCreated},
+		{"user can mint any scope", nil, `["users:write","apikeys:manage"]`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &stubAPIKeys{}
+			handler := NewAPIKeyHandler(service)
+
+			body := `{"name":"ci","scopes":` + tt.scopes + `}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/"+userID.String()+"/api-keys", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("id", userID.String())
+			ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID.String())
+			if tt.caller != nil {
+				ctx = context.WithValue(ctx, middleware.APIKeyKey, tt.caller)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.CreateAPIKey(rec, req.WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if created := len(service.created) == 1; created != (tt.wantStatus == http.StatusCreated) {
+				t.Errorf("key created = %v with status %d", created, rec.Code)
+			}
+		})
+	}
+}
\ No newline at end of file
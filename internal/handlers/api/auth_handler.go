@@ -1,20 +1,24 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
 
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
 	"starter-kit-fullstack-gonethttp-template/internal/services"
 	"starter-kit-fullstack-gonethttp-template/pkg/response"
-	"starter-kit-fullstack-gonethttp-template/pkg/utils"
 )
 
+// AuthHandler answers sign-ins with the token pair, and sets the session cookies with it
+// so a browser calling the API is signed in as well
 type AuthHandler struct {
 	service services.AuthService
+	cfg     *config.Config
 }
 
-func NewAuthHandler(service services.AuthService) *AuthHandler {
-	return &AuthHandler{service: service}
+func NewAuthHandler(service services.AuthService, cfg *config.Config) *AuthHandler {
+	return &AuthHandler{service: service, cfg: cfg}
 }
 
 // Register godoc
@@ -25,26 +29,22 @@ func NewAuthHandler(service services.AuthService) *AuthHandler {
 // @Produce json
 // @Param request body services.RegisterRequest true "Register Request"
 // @Success 201 {object} response.APIResponse{data=map[string]interface{}}
-// @Failure 400 {object} response.APIResponse
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
 // @Router /v1/auth/register [post]
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req services.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if errs := utils.ValidateStruct(req); errs != nil {
-		response.JSON(w, http.StatusBadRequest, map[string]interface{}{"code": 400, "message": errs})
-		return
-	}
-
-	user, tokens, err := h.service.Register(req)
+	user, tokens, err := h.service.Register(r.Context(), req)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
+	middleware.SetSessionCookies(w, h.cfg, tokens)
 	response.JSON(w, http.StatusCreated, map[string]interface{}{
 		"user":   user,
 		"tokens": tokens,
@@ -53,35 +53,33 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user with email and password
+// @Description Authenticate user with email and password. Accounts that require a passkey get {passkeyRequired, loginToken} instead of tokens
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body object{email=string,password=string} true "Login Request"
 // @Success 200 {object} response.APIResponse{data=map[string]interface{}}
-// @Failure 401 {object} response.APIResponse
+// @Failure 401 {object} response.Problem
 // @Router /v1/auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email    string `json:"email" validate:"required,email"`
 		Password string `json:"password" validate:"required"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if errs := utils.ValidateStruct(req); errs != nil {
-		response.JSON(w, http.StatusBadRequest, map[string]interface{}{"code": 400, "message": errs})
+	user, tokens, err := h.service.Login(r.Context(), req.Email, req.Password, middleware.ClientInfo(r))
+	if passkeyRequired(w, err) {
 		return
 	}
-
-	user, tokens, err := h.service.Login(req.Email, req.Password)
 	if err != nil {
-		response.Error(w, http.StatusUnauthorized, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
+	middleware.SetSessionCookies(w, h.cfg, tokens)
 	response.Success(w, http.StatusOK, map[string]interface{}{
 		"user":   user,
 		"tokens": tokens,
@@ -96,20 +94,17 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param request body object{refreshToken=string} true "Logout Request"
 // @Success 204 "No Content"
-// @Failure 400 {object} response.APIResponse
+// @Failure 400 {object} response.Problem
 // @Router /v1/auth/logout [post]
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		RefreshToken string `json:"refreshToken"`
+		RefreshToken string `json:"refreshToken" validate:"required"`
 	}
-	json.NewDecoder(r.Body).Decode(&req)
-
-	if req.RefreshToken == "" {
-		response.Error(w, http.StatusBadRequest, "refreshToken is required")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	h.service.Logout(req.RefreshToken)
+	h.service.Logout(r.Context(), req.RefreshToken)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -121,22 +116,19 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param request body object{refreshToken=string} true "Refresh Request"
 // @Success 200 {object} response.APIResponse{data=map[string]interface{}}
-// @Failure 401 {object} response.APIResponse
+// @Failure 401 {object} response.Problem
 // @Router /v1/auth/refresh-tokens [post]
 func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		RefreshToken string `json:"refreshToken"`
+		RefreshToken string `json:"refreshToken" validate:"required"`
 	}
-	json.NewDecoder(r.Body).Decode(&req)
-
-	if req.RefreshToken == "" {
-		response.Error(w, http.StatusBadRequest, "refreshToken is required")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	tokens, err := h.service.RefreshAuth(req.RefreshToken)
+	tokens, err := h.service.RefreshAuth(r.Context(), req.RefreshToken)
 	if err != nil {
-		response.Error(w, http.StatusUnauthorized, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
@@ -151,26 +143,79 @@ func (h *AuthHandler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param request body object{email=string} true "Forgot Password Request"
 // @Success 204 "No Content"
-// @Failure 400 {object} response.APIResponse
+// @Failure 400 {object} response.Problem
 // @Router /v1/auth/forgot-password [post]
 func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email string `json:"email" validate:"required,email"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid request")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if errs := utils.ValidateStruct(req); errs != nil {
-		response.JSON(w, http.StatusBadRequest, map[string]interface{}{"code": 400, "message": errs})
+	h.service.ForgotPassword(r.Context(), req.Email)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MagicLink godoc
+// @Summary Request a sign-in link
+// @Description Email a single-use passwordless sign-in link. Always 204 for well-formed requests, whether or not the account exists
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body object{email=string} true "Magic Link Request"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Problem
+// @Failure 429 {object} response.Problem
+// @Router /v1/auth/magic-link [post]
+func (h *AuthHandler) MagicLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	h.service.ForgotPassword(req.Email)
+	if err := h.service.RequestMagicLink(r.Context(), req.Email); errors.Is(err, services.ErrMagicLinkRateLimited) {
+		respondError(w, r, err)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// MagicLogin godoc
+// @Summary Sign in with a magic link
+// @Description Exchanges the token from a sign-in email for an access/refresh pair. Send Accept: application/json, browsers without it get the confirmation page
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Magic Link Token"
+// @Success 200 {object} response.APIResponse{data=map[string]interface{}}
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Router /auth/magic [get]
+func (h *AuthHandler) MagicLogin(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.Error(w, r, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	user, tokens, err := h.service.LoginWithMagicLink(r.Context(), token, middleware.ClientInfo(r))
+	if passkeyRequired(w, err) {
+		return
+	}
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	middleware.SetSessionCookies(w, h.cfg, tokens)
+	response.Success(w, http.StatusOK, map[string]interface{}{
+		"user":   user,
+		"tokens": tokens,
+	})
+}
+
 // ResetPassword godoc
 // @Summary Reset password
 // @Description Reset password using a valid token
@@ -180,24 +225,68 @@ func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 // @Param token query string true "Reset Token"
 // @Param request body object{password=string} true "Reset Password Request"
 // @Success 204 "No Content"
-// @Failure 400 {object} response.APIResponse
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
 // @Router /v1/auth/reset-password [post]
 func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	token := r.URL.Query().Get("token")
 	var req struct {
-		Password string `json:"password" validate:"required,min=8"`
+		Password string `json:"password" validate:"required,password"`
+	}
+	if token == "" {
+		response.Error(w, r, http.StatusBadRequest, "Token is required")
+		return
+	}
+	if !decodeJSON(w, r, &req) {
+		return
 	}
-	json.NewDecoder(r.Body).Decode(&req)
 
-	if token == "" || req.Password == "" {
-		response.Error(w, http.StatusBadRequest, "Token and Password are required")
+	if err := h.service.ResetPassword(r.Context(), token, req.Password); err != nil {
+		respondError(w, r, err)
 		return
 	}
 
-	if err := h.service.ResetPassword(token, req.Password); err != nil {
-		response.Error(w, http.StatusBadRequest, err.Error())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Verify an email address (or a pending email change) using the emailed token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token query string true "Verify Email Token"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /v1/auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.Error(w, r, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	if err := h.service.VerifyEmail(r.Context(), token); err != nil {
+		respondError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
+}
+
+// passkeyRequired answers a sign-in that still needs the passkey step; the client
+// continues at POST /v1/auth/webauthn/login/begin with the loginToken
+func passkeyRequired(w http.ResponseWriter, err error) bool {
+	var required *services.PasskeyRequiredError
+	if !errors.As(err, &required) {
+		return false
+	}
+
+	response.Success(w, http.StatusOK, map[string]interface{}{
+		"passkeyRequired": true,
+		"loginToken":      required.Token,
+	})
+	return true
 }
\ No newline at end of file
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/request"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+)
+
+var kindStatus = map[services.ErrorKind]int{
+	services.KindValidation:   http.StatusBadRequest,
+	services.KindUnauthorized: http.StatusUnauthorized,
+	services.KindForbidden:    http.StatusForbidden,
+	services.KindNotFound:     http.StatusNotFound,
+	services.KindConflict:     http.StatusConflict,
+	services.KindRateLimited:  http.StatusTooManyRequests,
+}
+
+// respondError writes a service error as a problem document. Domain errors keep their
+// code and get their message translated; anything else is logged and answered with a generic 500 so
+// database or mailer details never reach the client.
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	var domainErr *services.Error
+	if !errors.As(err, &domainErr) {
+		slog.ErrorContext(r.Context(), "Unhandled error", "method", r.Method, "path", r.URL.Path, "error", err)
+		response.Error(w, r, http.StatusInternalServerError, "something went wrong, please try again later")
+		return
+	}
+
+	status, ok := kindStatus[domainErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	response.WriteProblem(w, response.Problem{
+		Status: status,
+		Detail: i18n.T(r.Context(), domainErr.Message),
+		Code:   domainErr.Code,
+	})
+}
+
+// decodeJSON decodes and validates the request body into dst. On failure it has already
+// answered the client and the handler just returns.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	return decoded(w, r, request.DecodeJSON(w, r, dst))
+}
+
+// decodeOptionalJSON is decodeJSON for routes where the body may be left out
+func decodeOptionalJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := request.DecodeJSON(w, r, dst)
+	if errors.Is(err, request.ErrEmptyBody) {
+		return true
+	}
+	return decoded(w, r, err)
+}
+
+// decoded writes a decoding error as a problem and reports whether there was none
+func decoded(w http.ResponseWriter, r *http.Request, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	var reqErr *request.Error
+	if !errors.As(err, &reqErr) {
+		// Not a client mistake DecodeJSON knows about, dst may be half filled
+		respondError(w, r, err)
+		return false
+	}
+
+	if reqErr.Fields != nil {
+		response.ValidationError(w, r, reqErr.Fields)
+		return false
+	}
+	response.WriteProblem(w, response.Problem{
+		Status: reqErr.Status,
+		Detail: i18n.T(r.Context(), reqErr.Message),
+		Code:   reqErr.Code,
+	})
+	return false
+}
\ No newline at end of file
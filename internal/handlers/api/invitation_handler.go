@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+type InvitationHandler struct {
+	service services.InvitationService
+	cfg     *config.Config
+}
+
+func NewInvitationHandler(service services.InvitationService, cfg *config.Config) *InvitationHandler {
+	return &InvitationHandler{service: service, cfg: cfg}
+}
+
+// CreateInvitation godoc
+// @Summary Invite a user (Admin)
+// @Description Email an invitation link; the invitee chooses their own name and password
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.InviteUserRequest true "Invite User Request"
+// @Success 201 {object} models.Invitation
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /v1/invitations [post]
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var req services.InviteUserRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	inviterID, _ := middleware.CurrentUserID(r)
+	invitation, err := h.service.Invite(r.Context(), inviterID, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, invitation)
+}
+
+// GetInvitations godoc
+// @Summary List open invitations (Admin)
+// @Description List invitations that have not been accepted yet, including expired ones
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Invitation
+// @Router /v1/invitations [get]
+func (h *InvitationHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.service.ListOpen(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, invitations)
+}
+
+// ResendInvitation godoc
+// @Summary Resend an invitation (Admin)
+// @Description Issue a new invite link (the previous one stops working) and email it again
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} models.Invitation
+// @Failure 404 {object} response.Problem
+// @Router /v1/invitations/{id}/resend [post]
+func (h *InvitationHandler) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid Invitation ID")
+		return
+	}
+
+	invitation, err := h.service.Resend(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, invitation)
+}
+
+// DeleteInvitation godoc
+// @Summary Revoke an invitation (Admin)
+// @Description Revoke a pending invitation so its link can no longer be used
+// @Tags Invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} response.Problem
+// @Router /v1/invitations/{id} [delete]
+func (h *InvitationHandler) DeleteInvitation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid Invitation ID")
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), id); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Create the invited account with a name and password, and return tokens
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token query string true "Invite Token"
+// @Param request body services.AcceptInviteRequest true "Accept Invite Request"
+// @Success 201 {object} response.APIResponse{data=map[string]interface{}}
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /v1/auth/accept-invite [post]
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.Error(w, r, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	var req services.AcceptInviteRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, tokens, err := h.service.Accept(r.Context(), token, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	middleware.SetSessionCookies(w, h.cfg, tokens)
+	response.JSON(w, http.StatusCreated, map[string]interface{}{
+		"user":   user,
+		"tokens": tokens,
+	})
+}
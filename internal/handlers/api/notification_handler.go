@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+)
+
+type NotificationHandler struct {
+	service services.NotificationService
+	cfg     *config.Config
+}
+
+func NewNotificationHandler(service services.NotificationService, cfg *config.Config) *NotificationHandler {
+	return &NotificationHandler{service: service, cfg: cfg}
+}
+
+// UpdateNotifications godoc
+// @Summary Update security email preferences
+// @Description Turn individual security notices on or off; omitted fields are left unchanged
+// @Tags Me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.UpdateNotificationsRequest true "Notification Preferences"
+// @Success 200 {object} models.User
+// @Failure 400 {object} response.Problem
+// @Router /v1/me/notifications [patch]
+func (h *NotificationHandler) UpdateNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req services.UpdateNotificationsRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.UpdatePreferences(r.Context(), userID, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, user)
+}
+
+// SecureAccount godoc
+// @Summary Secure account ("this wasn't me")
+// @Description Uses the link from a security email to sign the user out everywhere, revoke API keys and cancel a pending email change
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token query string true "Secure Account Token"
+// @Success 204 "No Content"
+// @Failure 400 {object} response.Problem
+// @Router /v1/auth/secure-account [post]
+func (h *NotificationHandler) SecureAccount(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		response.Error(w, r, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	if err := h.service.SecureAccount(r.Context(), token); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	// The session of this browser was revoked with the others
+	middleware.ClearSessionCookies(w, h.cfg)
+	w.WriteHeader(http.StatusNoContent)
+}
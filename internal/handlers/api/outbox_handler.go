@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+type OutboxHandler struct {
+	service services.OutboxService
+}
+
+func NewOutboxHandler(service services.OutboxService) *OutboxHandler {
+	return &OutboxHandler{service: service}
+}
+
+// GetEmails godoc
+// @Summary List queued emails (Admin)
+// @Description Outbound emails with their delivery status; bodies are never returned
+// @Tags Email Outbox
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, sending, sent or failed"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} utils.PaginationResult
+// @Router /v1/admin/emails [get]
+func (h *OutboxHandler) GetEmails(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	result, err := h.service.GetEmails(r.Context(), query.Get("status"), page, limit)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, result)
+}
+
+// RetryEmail godoc
+// @Summary Retry a failed email (Admin)
+// @Description Put a dead-lettered email back in the queue with a fresh set of attempts
+// @Tags Email Outbox
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Email ID"
+// @Success 200 {object} models.OutboxEmail
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /v1/admin/emails/{id}/retry [post]
+func (h *OutboxHandler) RetryEmail(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid Email ID")
+		return
+	}
+
+	email, err := h.service.Retry(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, email)
+}
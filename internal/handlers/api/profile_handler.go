@@ -0,0 +1,259 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+)
+
+// ProfileHandler serves the self-service endpoints under /v1/me.
+// The target user is always the authenticated one, never taken from the URL.
+type ProfileHandler struct {
+	userService    services.UserService
+	authService    services.AuthService
+	accountService services.AccountService
+	cfg            *config.Config
+}
+
+func NewProfileHandler(userService services.UserService, authService services.AuthService, accountService services.AccountService, cfg *config.Config) *ProfileHandler {
+	return &ProfileHandler{userService: userService, authService: authService, accountService: accountService, cfg: cfg}
+}
+
+// GetProfile godoc
+// @Summary Get own profile
+// @Description Get the profile of the authenticated user
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.User
+// @Failure 401 {object} response.Problem
+// @Router /v1/me [get]
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
+		return
+	}
+
+	user, err := h.userService.GetUserByID(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+// @Summary Update own profile
+// @Description Update the name and preferred language of the authenticated user. Use /v1/me/email and /v1/me/password for credentials.
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.UpdateProfileRequest true "Update Profile Request"
+// @Success 200 {object} models.User
+// @Failure 400 {object} response.Problem
+// @Router /v1/me [patch]
+func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
+		return
+	}
+
+	var req services.UpdateProfileRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.userService.UpdateUser(r.Context(), id, id, services.UpdateUserRequest{Name: req.Name, Locale: req.Locale})
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, user)
+}
+
+// ChangePassword godoc
+// @Summary Change own password
+// @Description Change password using the current one. All other sessions are revoked and a fresh token pair is returned.
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.ChangePasswordRequest true "Change Password Request"
+// @Success 200 {object} response.APIResponse{data=map[string]interface{}}
+// @Failure 400 {object} response.Problem
+// @Router /v1/me/password [post]
+func (h *ProfileHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
+		return
+	}
+
+	var req services.ChangePasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	tokens, err := h.authService.ChangePassword(r.Context(), id, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	// Every other session was revoked, this one goes on with the new pair
+	middleware.SetSessionCookies(w, h.cfg, tokens)
+	response.Success(w, http.StatusOK, map[string]interface{}{
+		"tokens": tokens,
+	})
+}
+
+// ChangeEmail godoc
+// @Summary Change own email
+// @Description Request an email change. The new address only becomes active after it is verified through the emailed link.
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.ChangeEmailRequest true "Change Email Request"
+// @Success 202 "Accepted"
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /v1/me/email [post]
+func (h *ProfileHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
+		return
+	}
+
+	var req services.ChangeEmailRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(r.Context(), id, req); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ExportData godoc
+// @Summary Export own data
+// @Description Download a copy of the authenticated user's data: profile, active sessions, API keys, devices, passkeys and the audit log of account changes
+// @Tags Profile
+// @Produce json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param format query string false "Archive format (json or zip)"
+// @Success 200 {object} services.AccountExport
+// @Failure 400 {object} response.Problem
+// @Router /v1/me/export [get]
+func (h *ProfileHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		response.Error(w, r, http.StatusBadRequest, "format must be one of: json, zip")
+		return
+	}
+
+	export, err := h.accountService.ExportData(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	if format != "zip" {
+		w.Header().Set("Content-Disposition", `attachment; filename="account-export.json"`)
+		response.Success(w, http.StatusOK, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="account-export.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	// One JSON document per data category, always in the same order
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"sessions.json", export.Sessions},
+		{"api_keys.json", export.APIKeys},
+		{"devices.json", export.Devices},
+		{"passkeys.json", export.Passkeys},
+		{"audit_log.json", export.AuditLog},
+	}
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return
+		}
+	}
+	archive.Close()
+}
+
+// DeleteAccount godoc
+// @Summary Delete own account
+// @Description Delete the authenticated user's account. Every session and API key is revoked immediately; the data is purged after the configured grace period unless the user signs in again.
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.DeleteAccountRequest true "Delete Account Request"
+// @Success 202 {object} object{deletionScheduledAt=string}
+// @Success 204 "No Content (deleted immediately)"
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
+// @Router /v1/me [delete]
+func (h *ProfileHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	id, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
+		return
+	}
+
+	var req services.DeleteAccountRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	purgeAt, err := h.accountService.ScheduleDeletion(r.Context(), id, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	if purgeAt == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, map[string]interface{}{
+		"deletionScheduledAt": purgeAt,
+	})
+}
\ No newline at end of file
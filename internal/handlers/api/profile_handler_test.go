@@ -0,0 +1,52 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// stubAccounts exports an account with nothing but its profile
+type stubAccounts struct {
+	services.AccountService
+}
+
+func (stubAccounts) ExportData(ctx context.Context, userID uuid.UUID) (*services.AccountExport, error) {
+	return &services.AccountExport{ExportedAt: time.Now(), Profile: &models.User{ID: userID}}, nil
+}
+
+func TestExportZIPFileOrder(t *testing.T) {
+	handler := NewProfileHandler(nil, nil, stubAccounts{}, nil)
+	want := []string{"profile.json", "sessions.json", "api_keys.json", "devices.json", "passkeys.json", "audit_log.json"}
+
+	// Map iteration would reorder the files sooner or later, a few downloads show it
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/me/export?format=zip", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, uuid.NewString()))
+		rec := httptest.NewRecorder()
+		handler.ExportData(rec, req)
+
+		archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if err != nil {
+			t.Fatalf("status %d, not a ZIP: %v", rec.Code, err)
+		}
+		var names []string
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		if !slices.Equal(names, want) {
+			t.Fatalf("download %d has files %v, want %v", i+1, names, want)
+		}
+	}
+}
\ No newline at end of file
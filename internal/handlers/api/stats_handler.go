@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+)
+
+type StatsHandler struct {
+	service services.StatsService
+}
+
+func NewStatsHandler(service services.StatsService) *StatsHandler {
+	return &StatsHandler{service: service}
+}
+
+// GetStats godoc
+// @Summary Dashboard statistics (Admin)
+// @Description User counts by role and verification, signups per interval, active sessions and the latest sign-in of each known device. Results are cached for a minute.
+// @Tags Stats
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Start of the period, a date (2026-01-01) or RFC 3339 time. Defaults to 30 days before to"
+// @Param to query string false "End of the period, a date (inclusive) or RFC 3339 time. Defaults to now"
+// @Param interval query string false "Signup buckets: day (default), week or month"
+// @Success 200 {object} services.Stats
+// @Failure 400 {object} response.Problem
+// @Router /v1/admin/stats [get]
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	stats, err := h.service.GetStats(r.Context(), services.StatsQuery{
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		Interval: query.Get("interval"),
+	})
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, stats)
+}
\ No newline at end of file
@@ -1,13 +1,12 @@
 package api
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
 	"starter-kit-fullstack-gonethttp-template/internal/services"
 	"starter-kit-fullstack-gonethttp-template/pkg/response"
-	"starter-kit-fullstack-gonethttp-template/pkg/utils"
 
 	"github.com/google/uuid"
 )
@@ -29,23 +28,18 @@ func NewUserHandler(service services.UserService) *UserHandler {
 // @Security BearerAuth
 // @Param request body services.CreateUserRequest true "Create User Request"
 // @Success 201 {object} models.User
-// @Failure 400 {object} response.APIResponse
+// @Failure 400 {object} response.Problem
+// @Failure 409 {object} response.Problem
 // @Router /v1/users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req services.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if errs := utils.ValidateStruct(req); errs != nil {
-		response.JSON(w, http.StatusBadRequest, map[string]interface{}{"code": 400, "message": errs})
-		return
-	}
-
-	user, err := h.service.CreateUser(req)
+	user, err := h.service.CreateUser(r.Context(), req)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
@@ -96,9 +90,9 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 		opts.Search = s
 	}
 
-	result, err := h.service.GetUsers(opts)
+	result, err := h.service.GetUsers(r.Context(), opts)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
@@ -114,19 +108,19 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 // @Security BearerAuth
 // @Param id path string true "User ID"
 // @Success 200 {object} models.User
-// @Failure 404 {object} response.APIResponse
+// @Failure 404 {object} response.Problem
 // @Router /v1/users/{id} [get]
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid User ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid User ID")
 		return
 	}
 
-	user, err := h.service.GetUserByID(id)
+	user, err := h.service.GetUserByID(r.Context(), id)
 	if err != nil {
-		response.Error(w, http.StatusNotFound, "User not found")
+		respondError(w, r, err)
 		return
 	}
 
@@ -135,7 +129,7 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 // UpdateUser godoc
 // @Summary Update user
-// @Description Update user details
+// @Description Update user details. Changing the role revokes the user's sessions; admins cannot change their own role or demote the last admin.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -143,25 +137,27 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "User ID"
 // @Param request body services.UpdateUserRequest true "Update Request"
 // @Success 200 {object} models.User
-// @Failure 400 {object} response.APIResponse
+// @Failure 400 {object} response.Problem
+// @Failure 403 {object} response.Problem
+// @Failure 409 {object} response.Problem
 // @Router /v1/users/{id} [patch]
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid User ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid User ID")
 		return
 	}
 
 	var req services.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	user, err := h.service.UpdateUser(id, req)
+	actorID, _ := middleware.CurrentUserID(r)
+	user, err := h.service.UpdateUser(r.Context(), actorID, id, req)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err.Error())
+		respondError(w, r, err)
 		return
 	}
 
@@ -170,25 +166,28 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 // DeleteUser godoc
 // @Summary Delete user
-// @Description Permanently delete a user
+// @Description Permanently delete a user. Admins cannot delete themselves or the last admin.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "User ID"
 // @Success 204 "No Content"
-// @Failure 404 {object} response.APIResponse
+// @Failure 403 {object} response.Problem
+// @Failure 404 {object} response.Problem
+// @Failure 409 {object} response.Problem
 // @Router /v1/users/{id} [delete]
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "Invalid User ID")
+		response.Error(w, r, http.StatusBadRequest, "Invalid User ID")
 		return
 	}
 
-	if err := h.service.DeleteUser(id); err != nil {
-		response.Error(w, http.StatusNotFound, "User not found")
+	actorID, _ := middleware.CurrentUserID(r)
+	if err := h.service.DeleteUser(r.Context(), actorID, id); err != nil {
+		respondError(w, r, err)
 		return
 	}
 
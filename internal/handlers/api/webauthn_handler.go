@@ -0,0 +1,281 @@
+package api
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+
+	"github.com/google/uuid"
+)
+
+type WebAuthnHandler struct {
+	service services.WebAuthnService
+	cfg     *config.Config
+}
+
+func NewWebAuthnHandler(service services.WebAuthnService, cfg *config.Config) *WebAuthnHandler {
+	return &WebAuthnHandler{service: service, cfg: cfg}
+}
+
+// BeginRegistration godoc
+// @Summary Start passkey registration
+// @Description Returns the options for navigator.credentials.create() and the session ID to send back when finishing
+// @Tags Passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.APIResponse{data=map[string]interface{}}
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem "Called with an API key"
+// @Router /v1/auth/webauthn/register/begin [post]
+func (h *WebAuthnHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	options, sessionID, err := h.service.BeginRegistration(r.Context(), userID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, map[string]interface{}{
+		"sessionId": sessionID,
+		"options":   options,
+	})
+}
+
+// FinishRegistration godoc
+// @Summary Finish passkey registration
+// @Description Verifies the attestation returned by the authenticator and stores the passkey
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param session query string true "Session ID from the begin step"
+// @Param name query string false "Passkey name"
+// @Param request body object true "PublicKeyCredential from navigator.credentials.create()"
+// @Success 201 {object} models.WebAuthnCredential
+// @Failure 400 {object} response.Problem
+// @Failure 401 {object} response.Problem
+// @Failure 403 {object} response.Problem "Called with an API key"
+// @Router /v1/auth/webauthn/register/finish [post]
+func (h *WebAuthnHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.URL.Query().Get("session"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid session")
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if len(name) > 64 {
+		response.Error(w, r, http.StatusBadRequest, "Name must be at most 64 characters")
+		return
+	}
+
+	credential, err := h.service.FinishRegistration(r.Context(), userID, sessionID, name, r.Body)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, credential)
+}
+
+// BeginLogin godoc
+// @Summary Start passkey sign-in
+// @Description Without a body this starts a passwordless sign-in. With the loginToken from a passkeyRequired login response it starts the second factor for that user
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Param request body object{loginToken=string} false "Second factor"
+// @Success 200 {object} response.APIResponse{data=map[string]interface{}}
+// @Failure 400 {object} response.Problem
+// @Router /v1/auth/webauthn/login/begin [post]
+func (h *WebAuthnHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LoginToken string `json:"loginToken"`
+	}
+	if !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+
+	options, sessionID, err := h.service.BeginLogin(r.Context(), req.LoginToken)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, map[string]interface{}{
+		"sessionId": sessionID,
+		"options":   options,
+	})
+}
+
+// FinishLogin godoc
+// @Summary Finish passkey sign-in
+// @Description Verifies the assertion returned by the authenticator and returns the usual token pair
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Param session query string true "Session ID from the begin step"
+// @Param request body object true "PublicKeyCredential from navigator.credentials.get()"
+// @Success 200 {object} response.APIResponse{data=map[string]interface{}}
+// @Failure 401 {object} response.Problem
+// @Failure 400 {object} response.Problem
+// @Router /v1/auth/webauthn/login/finish [post]
+func (h *WebAuthnHandler) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(r.URL.Query().Get("session"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid session")
+		return
+	}
+
+	user, tokens, err := h.service.FinishLogin(r.Context(), sessionID, r.Body, middleware.ClientInfo(r))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	middleware.SetSessionCookies(w, h.cfg, tokens)
+	response.Success(w, http.StatusOK, map[string]interface{}{
+		"user":   user,
+		"tokens": tokens,
+	})
+}
+
+// GetPasskeys godoc
+// @Summary List own passkeys
+// @Tags Passkeys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.WebAuthnCredential
+// @Router /v1/me/passkeys [get]
+func (h *WebAuthnHandler) GetPasskeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	credentials, err := h.service.ListCredentials(r.Context(), userID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, credentials)
+}
+
+// RenamePasskey godoc
+// @Summary Rename a passkey
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Passkey ID"
+// @Param request body services.RenamePasskeyRequest true "New Name"
+// @Success 200 {object} models.WebAuthnCredential
+// @Failure 404 {object} response.Problem
+// @Router /v1/me/passkeys/{id} [patch]
+func (h *WebAuthnHandler) RenamePasskey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid Passkey ID")
+		return
+	}
+
+	var req services.RenamePasskeyRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	credential, err := h.service.RenameCredential(r.Context(), userID, id, req.Name)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, credential)
+}
+
+// DeletePasskey godoc
+// @Summary Remove a passkey
+// @Description Removing the last passkey also turns off the passkey requirement
+// @Tags Passkeys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Passkey ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} response.Problem
+// @Failure 403 {object} response.Problem "Called with an API key"
+// @Router /v1/me/passkeys/{id} [delete]
+func (h *WebAuthnHandler) DeletePasskey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		response.Error(w, r, http.StatusBadRequest, "Invalid Passkey ID")
+		return
+	}
+
+	if err := h.service.DeleteCredential(r.Context(), userID, id); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetPasskeyRequired godoc
+// @Summary Require a passkey as second factor
+// @Description When on, password and magic-link sign-ins answer with passkeyRequired and a loginToken instead of tokens
+// @Tags Passkeys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.PasskeyRequiredRequest true "Setting"
+// @Success 200 {object} models.User
+// @Failure 400 {object} response.Problem
+// @Failure 403 {object} response.Problem "Called with an API key"
+// @Router /v1/me/passkeys/required [put]
+func (h *WebAuthnHandler) SetPasskeyRequired(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.CurrentUserID(r)
+	if !ok {
+		response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req services.PasskeyRequiredRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.SetPasskeyRequired(r.Context(), userID, *req.Required)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	response.Success(w, http.StatusOK, user)
+}
\ No newline at end of file
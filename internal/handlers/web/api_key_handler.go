@@ -0,0 +1,24 @@
+package web
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+type APIKeyHandler struct{}
+
+func NewAPIKeyHandler() *APIKeyHandler {
+	return &APIKeyHandler{}
+}
+
+// Index lists the API keys of the signed in user, whose ID the page's script calls the API with
+func (h *APIKeyHandler) Index(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.CurrentUserID(r)
+	view.Render(w, r, "api-keys/index", map[string]interface{}{
+		"Title":     "API Keys",
+		"PageTitle": "API Keys",
+		"UserID":    userID,
+	}, "main")
+}
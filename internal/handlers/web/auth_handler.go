@@ -1,30 +1,305 @@
 package web
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
+	"strings"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/flash"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/validation"
 	"starter-kit-fullstack-gonethttp-template/pkg/view"
 )
 
-type AuthHandler struct{}
+type AuthHandler struct {
+	service services.AuthService
+	cfg     *config.Config
+}
 
-func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{}
+func NewAuthHandler(service services.AuthService, cfg *config.Config) *AuthHandler {
+	return &AuthHandler{service: service, cfg: cfg}
 }
 
 func (h *AuthHandler) ViewLogin(w http.ResponseWriter, r *http.Request) {
 	view.Render(w, r, "auth/login", map[string]interface{}{
 		"Title": "Login",
+		"Next":  r.URL.Query().Get("next"),
 	}, "auth")
 }
 
+type loginForm struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// Login signs in with the login form: the server sets the session cookies and the
+// visitor goes on to the page that sent them here. Accounts that require a passkey get
+// the login page back, which continues with the passkey.
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	next := middleware.LocalPath(r.PostFormValue("next"))
+	req := loginForm{
+		Email:    strings.TrimSpace(r.PostFormValue("email")),
+		Password: r.PostFormValue("password"),
+	}
+
+	back := "/login"
+	if next != "/" {
+		back += "?next=" + url.QueryEscape(next)
+	}
+	retry := func(message string) {
+		flash.Add(w, r, flash.Error, message)
+		flash.KeepInput(w, r)
+		http.Redirect(w, r, back, http.StatusSeeOther)
+	}
+
+	if errs := validation.Struct(r.Context(), req); errs != nil {
+		if message, ok := errs["email"]; ok {
+			retry(message)
+		} else {
+			retry(errs["password"])
+		}
+		return
+	}
+
+	user, tokens, err := h.service.Login(r.Context(), req.Email, req.Password, middleware.ClientInfo(r))
+	var passkey *services.PasskeyRequiredError
+	switch {
+	case errors.As(err, &passkey):
+		view.Render(w, r, "auth/login", map[string]interface{}{
+			"Title":      "Login",
+			"Next":       next,
+			"LoginToken": passkey.Token,
+		}, "auth")
+		return
+	case err != nil:
+		if message, ok := formError(w, r, err); ok {
+			retry(message)
+		}
+		return
+	}
+
+	middleware.SetSessionCookies(w, h.cfg, tokens)
+	setLocaleCookie(w, user.Locale)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// Logout revokes the session of this browser and clears its cookies
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(middleware.RefreshTokenCookie); err == nil {
+		// An already revoked session is just as signed out
+		h.service.Logout(r.Context(), cookie.Value)
+	}
+	middleware.ClearSessionCookies(w, h.cfg)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// setLocaleCookie switches the pages to the language of the user who signed in, ""
+// follows the browser again
+func setLocaleCookie(w http.ResponseWriter, locale string) {
+	cookie := &http.Cookie{Name: middleware.LocaleCookie, Value: locale, Path: "/", MaxAge: 365 * 24 * 60 * 60, SameSite: http.SameSiteLaxMode}
+	if locale == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
 func (h *AuthHandler) ViewRegister(w http.ResponseWriter, r *http.Request) {
 	view.Render(w, r, "auth/register", map[string]interface{}{
 		"Title": "Register",
 	}, "auth")
 }
 
+// Register creates an account with the register form and signs it in. Problems go back
+// to the form as flash messages, with what was typed in.
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	req := services.RegisterRequest{
+		Name:     strings.TrimSpace(r.PostFormValue("name")),
+		Email:    strings.TrimSpace(r.PostFormValue("email")),
+		Password: r.PostFormValue("password"),
+	}
+
+	retry := func(messages ...string) {
+		for _, message := range messages {
+			flash.Add(w, r, flash.Error, message)
+		}
+		flash.KeepInput(w, r)
+		http.Redirect(w, r, "/register", http.StatusSeeOther)
+	}
+
+	if errs := validation.Struct(r.Context(), req); errs != nil {
+		var messages []string
+		for _, field := range []string{"name", "email", "password"} {
+			if message, ok := errs[field]; ok {
+				messages = append(messages, message)
+			}
+		}
+		retry(messages...)
+		return
+	}
+
+	user, tokens, err := h.service.Register(r.Context(), req)
+	if err != nil {
+		if message, ok := formError(w, r, err); ok {
+			retry(message)
+		}
+		return
+	}
+
+	middleware.SetSessionCookies(w, h.cfg, tokens)
+	flash.Add(w, r, flash.Success, i18n.T(r.Context(), "Welcome, {name}! Your account is ready.", "name", user.Name))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// ViewMagicLink confirms before the single-use token is spent, so link scanners can't use it up
+func (h *AuthHandler) ViewMagicLink(w http.ResponseWriter, r *http.Request) {
+	view.Render(w, r, "auth/magic-link", map[string]interface{}{
+		"Title": "Sign In",
+		"Token": r.URL.Query().Get("token"),
+	}, "auth")
+}
+
 func (h *AuthHandler) ViewForgotPassword(w http.ResponseWriter, r *http.Request) {
 	view.Render(w, r, "auth/forgot-password", map[string]interface{}{
 		"Title": "Forgot Password",
 	}, "auth")
+}
+
+type forgotPasswordForm struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type resetPasswordForm struct {
+	Password     string `json:"password" validate:"required,password"`
+	Confirmation string `json:"password_confirmation" validate:"eqfield=Password"`
+}
+
+// ForgotPassword sends the reset link the forgot password form asks for, then goes on
+// to the login page with the address filled in. Like the API it never tells whether
+// the address has an account.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	req := forgotPasswordForm{Email: strings.TrimSpace(r.PostFormValue("email"))}
+
+	if errs := validation.Struct(r.Context(), req); errs != nil {
+		flash.Add(w, r, flash.Error, errs["email"])
+		flash.KeepInput(w, r)
+		http.Redirect(w, r, "/forgot-password", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.service.ForgotPassword(r.Context(), req.Email); err != nil {
+		renderServiceError(w, r, err)
+		return
+	}
+
+	flash.Add(w, r, flash.Success, i18n.T(r.Context(), "If an account exists for {email}, a reset link is on its way.", "email", req.Email))
+	flash.KeepInput(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// ViewResetPassword shows the new password form of an emailed reset link, or why the
+// link can't be used any more
+func (h *AuthHandler) ViewResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	h.renderResetPassword(w, r, token, h.service.CheckResetPasswordToken(r.Context(), token), nil)
+}
+
+// ResetPassword saves the password posted by the reset form, the link's token comes
+// along as a hidden field
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	req := resetPasswordForm{
+		Password:     r.PostFormValue("password"),
+		Confirmation: r.PostFormValue("password_confirmation"),
+	}
+
+	if errs := validation.Struct(r.Context(), req); errs != nil {
+		// A link that expired meanwhile should say so rather than ask for a better password
+		h.renderResetPassword(w, r, token, h.service.CheckResetPasswordToken(r.Context(), token), errs)
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), token, req.Password); err != nil {
+		h.renderResetPassword(w, r, token, err, nil)
+		return
+	}
+
+	flash.Add(w, r, flash.Success, i18n.T(r.Context(), "Your password has been reset. Sign in with the new one."))
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (h *AuthHandler) renderResetPassword(w http.ResponseWriter, r *http.Request, token string, err error, errs validation.Errors) {
+	data := map[string]interface{}{
+		"Title":  "Reset Password",
+		"Token":  token,
+		"Errors": errs,
+	}
+	status := http.StatusOK
+	if err != nil {
+		if !linkProblem(w, r, err, data) {
+			return
+		}
+		status = http.StatusBadRequest
+	} else if errs != nil {
+		status = http.StatusUnprocessableEntity
+	}
+	view.RenderStatus(w, r, status, "auth/reset-password", data, "auth")
+}
+
+// ViewVerifyEmail asks to confirm an emailed verification link before it is used, so
+// link scanners can't spend it
+func (h *AuthHandler) ViewVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	h.renderVerifyEmail(w, r, token, h.service.CheckVerifyEmailToken(r.Context(), token))
+}
+
+// VerifyEmail marks the address as verified, or switches to a pending new address
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	if err := h.service.VerifyEmail(r.Context(), token); err != nil {
+		h.renderVerifyEmail(w, r, token, err)
+		return
+	}
+
+	flash.Add(w, r, flash.Success, i18n.T(r.Context(), "Your email address is verified."))
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (h *AuthHandler) renderVerifyEmail(w http.ResponseWriter, r *http.Request, token string, err error) {
+	data := map[string]interface{}{
+		"Title": "Verify Email",
+		"Token": token,
+	}
+	status := http.StatusOK
+	if err != nil {
+		if !linkProblem(w, r, err, data) {
+			return
+		}
+		status = http.StatusBadRequest
+	}
+	view.RenderStatus(w, r, status, "auth/verify-email", data, "auth")
+}
+
+// linkProblem records in data why an emailed link can't be used: State is "expired" or
+// "invalid", or Error holds the message of another domain error (an address taken in
+// the meantime). The page answers with a 400 then. Unexpected failures get the error
+// page and false.
+func linkProblem(w http.ResponseWriter, r *http.Request, err error, data map[string]interface{}) bool {
+	switch {
+	case errors.Is(err, services.ErrTokenExpired):
+		data["State"] = "expired"
+	case errors.Is(err, services.ErrInvalidToken), errors.Is(err, services.ErrUserNotFound):
+		// A link of a deleted account is as dead as a made up one
+		data["State"] = "invalid"
+	default:
+		message, ok := formError(w, r, err)
+		if !ok {
+			return false
+		}
+		data["Error"] = message
+	}
+	return true
 }
\ No newline at end of file
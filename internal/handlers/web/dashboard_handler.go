@@ -2,17 +2,93 @@ package web
 
 import (
 	"net/http"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
 	"starter-kit-fullstack-gonethttp-template/pkg/view"
 )
 
-type DashboardHandler struct{}
+// statsRange is a period the dashboard offers, with the interval its chart uses
+type statsRange struct {
+	Days     int
+	Interval string
+}
+
+// statsRanges are keyed by the range query parameter, "30" is the default
+var statsRanges = map[string]statsRange{
+	"7":   {Days: 7, Interval: "day"},
+	"30":  {Days: 30, Interval: "day"},
+	"90":  {Days: 90, Interval: "week"},
+	"365": {Days: 365, Interval: "month"},
+}
+
+type DashboardHandler struct {
+	stats services.StatsService
+}
+
+func NewDashboardHandler(stats services.StatsService) *DashboardHandler {
+	return &DashboardHandler{stats: stats}
+}
 
-func NewDashboardHandler() *DashboardHandler {
-	return &DashboardHandler{}
+// signupBar is one bar of the signups chart, Height in percent of the tallest
+type signupBar struct {
+	Start  time.Time
+	Count  int64
+	Height int64
 }
 
+// Index greets the signed in user. Admins also get the statistics widgets for the
+// period picked with ?range=.
 func (h *DashboardHandler) Index(w http.ResponseWriter, r *http.Request) {
-	view.Render(w, r, "dashboard/index", map[string]interface{}{
-		"Title": "Dashboard",
-	}, "main")
+	user, _ := middleware.CurrentUser(r)
+	data := map[string]interface{}{
+		"Title":       "Dashboard",
+		"CurrentUser": user,
+	}
+
+	if user != nil && user.Role == models.RoleAdmin {
+		key := r.URL.Query().Get("range")
+		period, ok := statsRanges[key]
+		if !ok {
+			key, period = "30", statsRanges["30"]
+		}
+
+		stats, err := h.stats.GetStats(r.Context(), services.StatsQuery{
+			From:     time.Now().UTC().AddDate(0, 0, 1-period.Days).Format(time.DateOnly),
+			Interval: period.Interval,
+		})
+		if err != nil {
+			renderServiceError(w, r, err)
+			return
+		}
+
+		data["Range"] = key
+		data["Stats"] = stats
+		data["SignupBars"] = signupBars(stats.Signups.Buckets)
+		if stats.Users.Total > 0 {
+			data["VerifiedPercent"] = stats.Users.Verified * 100 / stats.Users.Total
+		} else {
+			data["VerifiedPercent"] = int64(0)
+		}
+	}
+
+	view.Render(w, r, "dashboard/index", data, "main")
+}
+
+func signupBars(buckets []services.StatsBucket) []signupBar {
+	var tallest int64
+	for _, bucket := range buckets {
+		tallest = max(tallest, bucket.Count)
+	}
+
+	bars := make([]signupBar, len(buckets))
+	for i, bucket := range buckets {
+		bars[i] = signupBar{Start: bucket.Start, Count: bucket.Count}
+		if tallest > 0 {
+			bars[i].Height = bucket.Count * 100 / tallest
+		}
+	}
+	return bars
 }
\ No newline at end of file
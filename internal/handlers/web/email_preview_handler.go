@@ -0,0 +1,77 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/pkg/mailer"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+// EmailPreviewHandler renders email templates with sample data (development only)
+type EmailPreviewHandler struct {
+	renderer *mailer.Renderer
+	cfg      *config.Config
+}
+
+func NewEmailPreviewHandler(renderer *mailer.Renderer, cfg *config.Config) *EmailPreviewHandler {
+	return &EmailPreviewHandler{renderer: renderer, cfg: cfg}
+}
+
+// Index lists every template and locale
+func (h *EmailPreviewHandler) Index(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.renderer.Templates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	locales, err := h.renderer.Locales()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	view.Render(w, r, "dev/emails", map[string]interface{}{
+		"Title":     "Email Previews",
+		"Templates": templates,
+		"Locales":   locales,
+	}, "main")
+}
+
+// Show renders one template: ?locale=id&format=text
+func (h *EmailPreviewHandler) Show(w http.ResponseWriter, r *http.Request) {
+	content, err := h.renderer.Render(r.PathValue("name"), r.URL.Query().Get("locale"), h.sampleData())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "Subject: %s\n\n%s", content.Subject, content.Text)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(content.HTML))
+}
+
+// sampleData covers the variables used by all templates
+func (h *EmailPreviewHandler) sampleData() map[string]interface{} {
+	return map[string]interface{}{
+		"AppName":   h.cfg.App.Name,
+		"AppURL":    h.cfg.App.URL,
+		"URL":       h.cfg.App.URL + "/preview?token=sample-token",
+		"PurgeAt":   time.Now().AddDate(0, 0, h.cfg.Account.DeletionGraceDays),
+		"ExpiresIn": h.cfg.JWT.MagicLinkExpiration,
+		// Security notifications
+		"Time":      time.Now(),
+		"IP":        "203.0.113.7",
+		"UserAgent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Firefox/128.0",
+		"NewEmail":  "new-address@example.com",
+		"Changes":   []string{"role", "email"},
+		"SecureURL": h.cfg.App.URL + "/secure-account?token=sample-token",
+	}
+}
@@ -0,0 +1,35 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+// formError turns a service error into a message to show above the form. Missing
+// records and unexpected failures get their error page instead, then ok is false and
+// the handler just returns.
+func formError(w http.ResponseWriter, r *http.Request, err error) (message string, ok bool) {
+	var domainErr *services.Error
+	if !errors.As(err, &domainErr) || domainErr.Kind == services.KindNotFound {
+		renderServiceError(w, r, err)
+		return "", false
+	}
+	return i18n.T(r.Context(), domainErr.Message), true
+}
+
+// renderServiceError answers with the 404 page for missing records and the 500 page,
+// logged, for anything else. Like the API, internal details never reach the page.
+func renderServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var domainErr *services.Error
+	if errors.As(err, &domainErr) && domainErr.Kind == services.KindNotFound {
+		view.RenderError(w, r, http.StatusNotFound)
+		return
+	}
+	slog.ErrorContext(r.Context(), "Unhandled error", "method", r.Method, "path", r.URL.Path, "error", err)
+	view.RenderError(w, r, http.StatusInternalServerError)
+}
\ No newline at end of file
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/mailer"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+// InboxHandler shows emails captured by the memory transport (EMAIL_TRANSPORT=memory)
+type InboxHandler struct {
+	inbox *mailer.MemoryTransport
+}
+
+func NewInboxHandler(inbox *mailer.MemoryTransport) *InboxHandler {
+	return &InboxHandler{inbox: inbox}
+}
+
+// Index lists captured emails, newest first
+func (h *InboxHandler) Index(w http.ResponseWriter, r *http.Request) {
+	view.Render(w, r, "dev/inbox", map[string]interface{}{
+		"Title":    "Dev Inbox",
+		"Messages": h.inbox.Messages(),
+	}, "main")
+}
+
+// Messages returns captured emails as JSON, for scripts that need a link from an email
+func (h *InboxHandler) Messages(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, http.StatusOK, h.inbox.Messages())
+}
+
+// Show renders one email: HTML by default, ?format=text or ?format=raw for the .eml source
+func (h *InboxHandler) Show(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	msg, ok := h.inbox.Get(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(msg.Text))
+	case "raw":
+		w.Header().Set("Content-Type", "message/rfc822")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="email-%d.eml"`, msg.ID))
+		w.Write(msg.Raw)
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(msg.HTML))
+	}
+}
+
+// Clear empties the inbox
+func (h *InboxHandler) Clear(w http.ResponseWriter, r *http.Request) {
+	h.inbox.Clear()
+	http.Redirect(w, r, "/dev/inbox", http.StatusSeeOther)
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+type InvitationHandler struct {
+	service services.InvitationService
+}
+
+func NewInvitationHandler(service services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{service: service}
+}
+
+// Index lists open invitations (Admin)
+func (h *InvitationHandler) Index(w http.ResponseWriter, r *http.Request) {
+	view.Render(w, r, "users/invitations", map[string]interface{}{
+		"Title":     "Invitations",
+		"PageTitle": "Users",
+	}, "main")
+}
+
+// ViewAcceptInvite validates the token before showing the sign-up form
+func (h *InvitationHandler) ViewAcceptInvite(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	data := map[string]interface{}{
+		"Title": i18n.T(r.Context(), "Accept Invitation"),
+		"Token": token,
+	}
+
+	invitation, err := h.service.GetPending(r.Context(), token)
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusBadRequest
+		data["Error"] = i18n.T(r.Context(), "This invitation link is invalid or has expired. Ask an administrator to send you a new one.")
+	} else {
+		data["Invitation"] = invitation
+	}
+
+	view.RenderStatus(w, r, status, "auth/accept-invite", data, "auth")
+}
@@ -0,0 +1,20 @@
+package web
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+type OutboxHandler struct{}
+
+func NewOutboxHandler() *OutboxHandler {
+	return &OutboxHandler{}
+}
+
+// Index shows the outbound email queue (Admin)
+func (h *OutboxHandler) Index(w http.ResponseWriter, r *http.Request) {
+	view.Render(w, r, "outbox/index", map[string]interface{}{
+		"Title": "Email Outbox",
+	}, "main")
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"net/url"
+	"strconv"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+)
+
+// pageWindow is how many page links are shown on each side of the current page
+const pageWindow = 2
+
+type pageLink struct {
+	Number int
+	URL    string
+	Active bool
+}
+
+// pagination is what a paged table needs to render its footer. The URLs keep the rest of
+// the query string, so paging does not lose the search.
+type pagination struct {
+	Page       int
+	TotalPages int
+	Total      int64
+	From, To   int64  // 1-based range of the rows shown, 0 when there are none
+	Current    string // this page's URL, to come back to after a form posted from it
+	Prev, Next string // "" on the first and last page
+	Links      []pageLink
+}
+
+func newPagination(u *url.URL, result *utils.PaginationResult) pagination {
+	limit, _ := result.Limit.(int)
+	p := pagination{Page: result.Page, TotalPages: result.TotalPages, Total: result.TotalResults, Current: u.RequestURI()}
+
+	if p.Total > 0 && limit > 0 {
+		p.From = int64((p.Page-1)*limit) + 1
+		p.To = min(p.From+int64(limit)-1, p.Total)
+	}
+
+	pageURL := func(page int) string {
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		return u.Path + "?" + query.Encode()
+	}
+	if p.Page > 1 {
+		p.Prev = pageURL(p.Page - 1)
+	}
+	if p.Page < p.TotalPages {
+		p.Next = pageURL(p.Page + 1)
+	}
+	for n := max(1, p.Page-pageWindow); n <= min(p.TotalPages, p.Page+pageWindow); n++ {
+		p.Links = append(p.Links, pageLink{Number: n, URL: pageURL(n), Active: n == p.Page})
+	}
+	return p
+}
\ No newline at end of file
@@ -0,0 +1,19 @@
+package web
+
+import (
+	"net/http"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+type ProfileHandler struct{}
+
+func NewProfileHandler() *ProfileHandler {
+	return &ProfileHandler{}
+}
+
+func (h *ProfileHandler) Index(w http.ResponseWriter, r *http.Request) {
+	view.Render(w, r, "profile/index", map[string]interface{}{
+		"Title":     "Profile",
+		"PageTitle": "Profile",
+	}, "main")
+}
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+)
+
+type SecureAccountHandler struct {
+	service services.NotificationService
+}
+
+func NewSecureAccountHandler(service services.NotificationService) *SecureAccountHandler {
+	return &SecureAccountHandler{service: service}
+}
+
+// ViewSecureAccount asks for confirmation before the "this wasn't me" link is used
+func (h *SecureAccountHandler) ViewSecureAccount(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	data := map[string]interface{}{
+		"Title": i18n.T(r.Context(), "Secure Your Account"),
+		"Token": token,
+	}
+
+	user, err := h.service.CheckSecureAccountToken(r.Context(), token)
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusBadRequest
+		data["Error"] = i18n.T(r.Context(), "This link is invalid, has expired or was already used. If you still think someone else has access to your account, reset your password.")
+	} else {
+		data["Email"] = user.Email
+	}
+
+	view.RenderStatus(w, r, status, "auth/secure-account", data, "auth")
+}
@@ -2,32 +2,220 @@ package web
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/flash"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/validation"
 	"starter-kit-fullstack-gonethttp-template/pkg/view"
+
+	"github.com/google/uuid"
 )
 
-type UserHandler struct{}
+const usersPerPage = 10
+
+// userSorts are the orders the list offers, the first is the default
+var userSorts = []string{"created_at:desc", "created_at:asc", "name:asc"}
+
+// UserHandler renders the admin's user management from UserService, every page works
+// without JavaScript: filters are a GET form, changes are forms that post back
+type UserHandler struct {
+	service services.UserService
+}
 
-func NewUserHandler() *UserHandler {
-	return &UserHandler{}
+func NewUserHandler(service services.UserService) *UserHandler {
+	return &UserHandler{service: service}
 }
 
+// Index lists users, searched, filtered and paged through the query string
 func (h *UserHandler) Index(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+
+	sortBy := query.Get("sortBy")
+	if !slices.Contains(userSorts, sortBy) {
+		sortBy = userSorts[0]
+	}
+
+	result, err := h.service.GetUsers(r.Context(), services.UserQueryOptions{
+		Page:        page,
+		Limit:       usersPerPage,
+		SortBy:      sortBy,
+		Search:      strings.TrimSpace(query.Get("search")),
+		SearchScope: "all",
+		RoleFilter:  query.Get("role"),
+	})
+	if err != nil {
+		renderServiceError(w, r, err)
+		return
+	}
+
 	view.Render(w, r, "users/index", map[string]interface{}{
-		"Title":     "User List",
-		"PageTitle": "Users",
+		"Title":      "User List",
+		"PageTitle":  "Users",
+		"Users":      result.Results,
+		"Pagination": newPagination(r.URL, result),
+		"Filter": map[string]string{
+			"search": query.Get("search"),
+			"role":   query.Get("role"),
+			"sortBy": sortBy,
+		},
 	}, "main")
 }
 
+// CreateView shows an empty user form
 func (h *UserHandler) CreateView(w http.ResponseWriter, r *http.Request) {
-	view.Render(w, r, "users/create", map[string]interface{}{
+	h.renderCreate(w, r, http.StatusOK, nil, "")
+}
+
+// Create adds the user posted by the create form
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	form := postedValues(r, "name", "email", "role")
+	req := services.CreateUserRequest{
+		RegisterRequest: services.RegisterRequest{
+			Name:     form["name"],
+			Email:    form["email"],
+			Password: r.PostFormValue("password"),
+		},
+		Role: form["role"],
+	}
+
+	if errs := validation.Struct(r.Context(), req); errs != nil {
+		h.renderCreate(w, r, http.StatusUnprocessableEntity, errs, "")
+		return
+	}
+
+	user, err := h.service.CreateUser(r.Context(), req)
+	if err != nil {
+		if message, ok := formError(w, r, err); ok {
+			h.renderCreate(w, r, http.StatusUnprocessableEntity, nil, message)
+		}
+		return
+	}
+
+	flash.Add(w, r, flash.Success, i18n.T(r.Context(), "User {name} was created", "name", user.Name))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// renderCreate shows the create form, after a failed post with what was posted
+func (h *UserHandler) renderCreate(w http.ResponseWriter, r *http.Request, status int, errs validation.Errors, message string) {
+	view.RenderStatus(w, r, status, "users/create", map[string]interface{}{
 		"Title":     "Create User",
 		"PageTitle": "Users",
+		"Form":      map[string]string{"name": "", "email": "", "role": models.RoleUser},
+		"Errors":    errs,
+		"Error":     message,
 	}, "main")
 }
 
+// EditView shows the form of an existing user
 func (h *UserHandler) EditView(w http.ResponseWriter, r *http.Request) {
-	view.Render(w, r, "users/edit", map[string]interface{}{
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	h.renderEdit(w, r, http.StatusOK, user, nil, "")
+}
+
+// Update saves the edit form. A blank password keeps the current one.
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	form := postedValues(r, "name", "email", "role")
+	req := services.UpdateUserRequest{
+		Name:     form["name"],
+		Email:    form["email"],
+		Password: r.PostFormValue("password"),
+		Role:     form["role"],
+	}
+
+	if errs := validation.Struct(r.Context(), req); errs != nil {
+		h.renderEdit(w, r, http.StatusUnprocessableEntity, user, errs, "")
+		return
+	}
+
+	actorID, _ := middleware.CurrentUserID(r)
+	updated, err := h.service.UpdateUser(r.Context(), actorID, user.ID, req)
+	if err != nil {
+		if message, ok := formError(w, r, err); ok {
+			h.renderEdit(w, r, http.StatusUnprocessableEntity, user, nil, message)
+		}
+		return
+	}
+
+	flash.Add(w, r, flash.Success, i18n.T(r.Context(), "User {name} was updated", "name", updated.Name))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// renderEdit shows the edit form filled in from user, after a failed post with what
+// was posted
+func (h *UserHandler) renderEdit(w http.ResponseWriter, r *http.Request, status int, user *models.User, errs validation.Errors, message string) {
+	view.RenderStatus(w, r, status, "users/edit", map[string]interface{}{
 		"Title":     "Edit User",
 		"PageTitle": "Users",
+		"User":      user,
+		"Form":      map[string]string{"name": user.Name, "email": user.Email, "role": user.Role},
+		"Errors":    errs,
+		"Error":     message,
 	}, "main")
+}
+
+// Delete removes a user and goes back to the list it was deleted from
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	back := r.PostFormValue("return")
+	if !strings.HasPrefix(back, "/users") {
+		back = "/users"
+	}
+
+	actorID, _ := middleware.CurrentUserID(r)
+	if err := h.service.DeleteUser(r.Context(), actorID, user.ID); err != nil {
+		message, ok := formError(w, r, err)
+		if !ok {
+			return
+		}
+		flash.Add(w, r, flash.Error, message)
+	} else {
+		flash.Add(w, r, flash.Success, i18n.T(r.Context(), "User {name} was deleted", "name", user.Name))
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// findUser loads the user named by the {id} path segment, answering with the 404 page
+// when there is none
+func (h *UserHandler) findUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		view.RenderError(w, r, http.StatusNotFound)
+		return nil, false
+	}
+
+	user, err := h.service.GetUserByID(r.Context(), id)
+	if err != nil {
+		renderServiceError(w, r, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// postedValues returns the trimmed form fields to validate
+func postedValues(r *http.Request, names ...string) map[string]string {
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		values[name] = strings.TrimSpace(r.PostFormValue(name))
+	}
+	return values
 }
\ No newline at end of file
@@ -1,12 +1,148 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/logger"
+	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// The browser session: the access token, and the refresh token that renews it on the
+// server once it expired. Both are set by the server and HttpOnly, forms and API calls
+// made with them are covered by CSRF.
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
 )
 
-// AuthCookie is a placeholder middleware for server-side cookie authentication.
-func AuthCookie(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		next.ServeHTTP(w, r)
-	})
+// SetSessionCookies signs the browser in with a token pair issued by AuthService.
+// Outside development the cookies are Secure, so they only travel over https.
+func SetSessionCookies(w http.ResponseWriter, cfg *config.Config, tokens map[string]interface{}) {
+	for name, kind := range map[string]string{AccessTokenCookie: "access", RefreshTokenCookie: "refresh"} {
+		token, _ := tokens[kind].(map[string]interface{})
+		value, _ := token["token"].(string)
+		expires, _ := token["expires"].(time.Time)
+		cookie := sessionCookie(cfg, name, value)
+		cookie.Expires = expires
+		http.SetCookie(w, cookie)
+	}
+}
+
+// ClearSessionCookies signs the browser out, the refresh token has to be revoked separately
+func ClearSessionCookies(w http.ResponseWriter, cfg *config.Config) {
+	for _, name := range []string{AccessTokenCookie, RefreshTokenCookie} {
+		cookie := sessionCookie(cfg, name, "")
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+	}
+}
+
+func sessionCookie(cfg *config.Config, name, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   cfg.App.Env != "development",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// SessionUserID returns who the session cookies belong to. An expired access token is
+// renewed with the refresh token on the way, which sets both cookies again.
+func SessionUserID(w http.ResponseWriter, r *http.Request, cfg *config.Config, authService services.AuthService) (uuid.UUID, bool) {
+	if cookie, err := r.Cookie(AccessTokenCookie); err == nil {
+		if id, ok := accessTokenSubject(cfg, cookie.Value); ok {
+			return id, true
+		}
+	}
+
+	cookie, err := r.Cookie(RefreshTokenCookie)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	// A failed refresh leaves the cookies alone: a request running in parallel may just
+	// have renewed them
+	tokens, err := authService.RefreshAuth(r.Context(), cookie.Value)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	SetSessionCookies(w, cfg, tokens)
+
+	access, _ := tokens["access"].(map[string]interface{})
+	token, _ := access["token"].(string)
+	return accessTokenSubject(cfg, token)
+}
+
+func accessTokenSubject(cfg *config.Config, token string) (uuid.UUID, bool) {
+	claims, err := utils.ValidateToken(token, cfg.JWT.Secret)
+	if err != nil || claims.Type != "access" {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(claims.Sub)
+	return id, err == nil
+}
+
+// AuthCookie authenticates page requests with the session cookies and loads the user,
+// whose language the page is rendered in.
+// Visitors without a session are sent to the login page, which brings them back.
+func AuthCookie(cfg *config.Config, userService services.UserService, authService services.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := SessionUserID(w, r, cfg, authService)
+			if !ok {
+				http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+				return
+			}
+			user, err := userService.GetUserByID(r.Context(), id)
+			if err != nil {
+				http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, id.String())
+			ctx = context.WithValue(ctx, UserKey, user)
+			ctx = logger.WithUserID(ctx, id.String())
+			ctx = withUserLocale(ctx, w, user)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GuestOnly sends visitors who are signed in already past the login and register pages,
+// on to ?next or the dashboard
+func GuestOnly(cfg *config.Config, authService services.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := SessionUserID(w, r, cfg, authService); ok {
+				http.Redirect(w, r, LocalPath(r.URL.Query().Get("next")), http.StatusSeeOther)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LocalPath returns next when it is a path on this site, "/" otherwise, so a crafted
+// ?next= can't send anyone elsewhere after signing in
+func LocalPath(next string) string {
+	if len(next) > 1 && next[0] == '/' && next[1] != '/' && next[1] != '\\' {
+		return next
+	}
+	return "/"
+}
+
+// CurrentUser returns the user loaded by AuthCookie or AuthJWT
+func CurrentUser(r *http.Request) (*models.User, bool) {
+	user, ok := r.Context().Value(UserKey).(*models.User)
+	return user, ok
 }
\ No newline at end of file
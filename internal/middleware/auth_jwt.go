@@ -2,12 +2,18 @@ package middleware
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"strings"
 
 	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/logger"
 	"starter-kit-fullstack-gonethttp-template/pkg/response"
-	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+
+	"github.com/google/uuid"
 )
 
 type contextKey string
@@ -15,34 +21,110 @@ type contextKey string
 const (
 	UserIDKey contextKey = "userID"
 	UserKey   contextKey = "user"
+	APIKeyKey contextKey = "apiKey"
 )
 
-func AuthJWT(cfg *config.Config, requiredRights []string) func(http.Handler) http.Handler {
+// AuthJWT authenticates requests using either a "Bearer <jwt>" access token
+// or an "ApiKey <key>" personal access token, loads the user and switches the request
+// to the user's language. Requests without either, like the calls of the web pages,
+// use the session cookies.
+// requiredRights only restrict API keys: the key must hold every listed scope.
+func AuthJWT(cfg *config.Config, userService services.UserService, apiKeyService services.APIKeyService, authService services.AuthService, requiredRights []string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				response.Error(w, http.StatusUnauthorized, "Please authenticate")
-				return
-			}
+			ctx := r.Context()
+			var userID uuid.UUID
 
-			// Format: "Bearer <token>"
+			// Format: "Bearer <token>" or "ApiKey <key>"
+			authHeader := r.Header.Get("Authorization")
 			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				response.Error(w, http.StatusUnauthorized, "Invalid token format")
+			switch {
+			case authHeader == "":
+				id, ok := SessionUserID(w, r, cfg, authService)
+				if !ok {
+					response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
+					return
+				}
+				userID = id
+			case len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey"):
+				response.Error(w, r, http.StatusUnauthorized, "Invalid token format")
 				return
+			case parts[0] == "ApiKey":
+				key, err := apiKeyService.Authenticate(r.Context(), parts[1])
+				if err != nil {
+					response.Error(w, r, http.StatusUnauthorized, "Invalid or expired API key")
+					return
+				}
+
+				for _, right := range requiredRights {
+					if !key.HasScope(right) {
+						response.Error(w, r, http.StatusForbidden, i18n.T(r.Context(), "Forbidden: API key lacks scope {scope}", "scope", right))
+						return
+					}
+				}
+
+				userID = key.UserID
+				ctx = context.WithValue(ctx, APIKeyKey, key)
+			default:
+				id, ok := accessTokenSubject(cfg, parts[1])
+				if !ok {
+					response.Error(w, r, http.StatusUnauthorized, "Invalid or expired token")
+					return
+				}
+				userID = id
 			}
 
-			tokenString := parts[1]
-			claims, err := utils.ValidateToken(tokenString, cfg.JWT.Secret)
-			if err != nil || claims.Type != "access" {
-				response.Error(w, http.StatusUnauthorized, "Invalid or expired token")
+			user, err := userService.GetUserByID(r.Context(), userID)
+			if err != nil {
+				response.Error(w, r, http.StatusUnauthorized, "Please authenticate")
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.Sub)
-			
+			ctx = context.WithValue(ctx, UserIDKey, userID.String())
+			ctx = context.WithValue(ctx, UserKey, user)
+			ctx = logger.WithUserID(ctx, userID.String())
+			ctx = withUserLocale(ctx, w, user)
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
+}
+
+// RejectAPIKey turns API keys away from routes that change how the user signs in, goes
+// after AuthJWT. A leaked key must not register a passkey of its own to get a full session,
+// or drop the passkeys protecting the account.
+func RejectAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := CurrentAPIKey(r); ok {
+			response.Error(w, r, http.StatusForbidden, "Forbidden: sign-in credentials can't be changed with an API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientInfo describes the device a request comes from, for security notices
+func ClientInfo(r *http.Request) services.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return services.ClientInfo{IP: ip, UserAgent: r.UserAgent()}
+}
+
+// CurrentAPIKey returns the API key the request authenticated with, when AuthJWT
+// accepted one rather than a user's token or session
+func CurrentAPIKey(r *http.Request) (*models.APIKey, bool) {
+	key, ok := r.Context().Value(APIKeyKey).(*models.APIKey)
+	return key, ok
+}
+
+// CurrentUserID returns the authenticated user's ID stored by AuthJWT.
+func CurrentUserID(r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := r.Context().Value(UserIDKey).(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(userIDStr)
+	return id, err == nil
 }
\ No newline at end of file
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/request"
+)
+
+// MaxBodySize caps request bodies of a route, replacing request.DefaultMaxBodyBytes
+func MaxBodySize(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, request.LimitBody(w, r, maxBytes))
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressibleTypes are the content types Compress encodes by default. Images, fonts
+// and archives are compressed already.
+var CompressibleTypes = []string{
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/javascript",
+	"text/csv",
+	"application/javascript",
+	"application/json",
+	"application/problem+json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// encoder is what gzip, brotli and zstd writers have in common
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encodings in order of preference when the client likes several equally
+var encodings = []string{"br", "zstd", "gzip"}
+
+var encoderPools = map[string]*sync.Pool{
+	// Fast levels: dynamic responses are compressed on every request
+	"br": {New: func() interface{} {
+		return brotli.NewWriterLevel(nil, 4)
+	}},
+	"zstd": {New: func() interface{} {
+		// Browsers refuse windows above 8 MB
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(8<<20))
+		return enc
+	}},
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// Compress encodes responses with brotli, zstd or gzip, whichever the client's
+// Accept-Encoding prefers. Only bodies of at least minSize bytes with one of types are
+// compressed; responses that set their own Content-Encoding (precompressed assets)
+// pass through. Until minSize bytes are written the body is held back, a Flush sends
+// it on right away.
+func Compress(minSize int, types []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// No body to compress, or a connection about to be taken over
+			if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+				minSize:        minSize,
+				types:          types,
+				status:         http.StatusOK,
+			}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks the supported encoding with the highest q value, "" for none
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 || q < bestQ {
+			continue
+		}
+		for _, enc := range encodings {
+			// On a tie the earlier entry of encodings wins
+			if name == enc && (q > bestQ || preference(enc) < preference(best)) {
+				best, bestQ = enc, q
+			}
+		}
+	}
+	return best
+}
+
+func preference(encoding string) int {
+	for i, enc := range encodings {
+		if enc == encoding {
+			return i
+		}
+	}
+	return len(encodings)
+}
+
+// compressWriter buffers the start of a response until it knows whether compressing it
+// pays off, then either streams it through an encoder or writes it out unchanged
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string // negotiated, "" if the client accepts none
+	minSize  int
+	types    []string
+	status   int
+
+	headerCalled bool // WriteHeader was called, the status is final
+	decided      bool
+	enc          encoder // set once compressing
+	buf          []byte
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	// Informational responses go out as they come, later calls are ignored like net/http does
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.headerCalled {
+		return
+	}
+	cw.status = code
+	cw.headerCalled = true
+
+	// Without a body, or with a length known to be too short, there is nothing to wait for
+	if !bodyAllowed(code) {
+		cw.decide(false)
+		return
+	}
+	if cl := cw.Header().Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < cw.minSize {
+			cw.decide(false)
+		}
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.headerCalled {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide sends the header, compressed if allowed and worthwhile, followed by the
+// buffered start of the body
+func (cw *compressWriter) decide(bigEnough bool) error {
+	cw.decided = true
+	h := cw.Header()
+
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// What net/http would have sniffed, needed before the encoding scrambles the bytes
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	eligible := bodyAllowed(cw.status) && cw.status != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" && h.Get("Content-Range") == "" &&
+		cw.allowedType(h.Get("Content-Type"))
+	if eligible {
+		// Another client may get a different body for the same URL
+		addVary(h, "Accept-Encoding")
+	}
+
+	if eligible && bigEnough && cw.encoding != "" {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The bytes differ from the identity body, so the tag is weak now
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.enc = encoderPools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) allowedType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, t := range cw.types {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush sends what was written so far. A stream should not wait for minSize, so the
+// response is compressed from here on even if it is still small.
+func (cw *compressWriter) Flush() {
+	if !cw.headerCalled {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.decide(true)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close finishes the response: it writes out a body that stayed below minSize, or ends
+// the compressed stream and returns the encoder to its pool
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.headerCalled {
+			// Nothing was written at all, let net/http send its default response
+			return nil
+		}
+		return cw.decide(false)
+	}
+	if cw.enc == nil {
+		return nil
+	}
+	err := cw.enc.Close()
+	cw.enc.Reset(nil)
+	encoderPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+	return err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (deadlines, hijacking)
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// addVary adds value to the Vary header unless it is listed already
+func addVary(h http.Header, value string) {
+	for _, line := range h.Values("Vary") {
+		for _, v := range strings.Split(line, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
\ No newline at end of file
@@ -4,8 +4,12 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"net/http"
 	"sync"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/request"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
 )
 
 // In-memory store for CSRF tokens
@@ -84,11 +88,20 @@ func CSRF(next http.Handler) http.Handler {
 		if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" || r.Method == "DELETE" {
 			clientToken := r.Header.Get("X-CSRF-TOKEN")
 			if clientToken == "" {
+				// The route's own body limit only applies further down, cap the form parsed here
+				r.Body = http.MaxBytesReader(w, r.Body, request.DefaultMaxBodyBytes)
+				if err := r.ParseForm(); err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						response.Error(w, r, http.StatusRequestEntityTooLarge, "")
+						return
+					}
+				}
 				clientToken = r.FormValue("csrf_token")
 			}
 
 			if token == "" || clientToken != token {
-				http.Error(w, "Invalid CSRF Token", http.StatusForbidden)
+				response.Error(w, r, http.StatusForbidden, "Invalid CSRF Token")
 				return
 			}
 		}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+)
+
+// LocaleCookie holds the language picked in the profile, the web UI sets it on sign-in
+const LocaleCookie = "locale"
+
+// Locale negotiates the language of the response from the locale cookie, then
+// Accept-Language, and stores it in the request context for i18n.T and validation
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var preferred string
+		if cookie, err := r.Cookie(LocaleCookie); err == nil {
+			preferred = cookie.Value
+		}
+		locale := i18n.Negotiate(preferred, r.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
+	})
+}
+
+// withUserLocale switches ctx to the language saved in the profile of the signed in user,
+// which wins over what Locale negotiated. Users without one keep the negotiated locale.
+func withUserLocale(ctx context.Context, w http.ResponseWriter, user *models.User) context.Context {
+	if !i18n.Supported(user.Locale) {
+		return ctx
+	}
+	w.Header().Set("Content-Language", user.Locale)
+	return i18n.WithLocale(ctx, user.Locale)
+}
\ No newline at end of file
@@ -3,21 +3,22 @@ package middleware
 import (
 	"log/slog"
 	"net/http"
-	"os"
 	"time"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/logger"
 )
 
+// Logger writes one record per request. The request context gets a log scope first,
+// so the user ID set by the auth middleware further in also lands on this record.
 func Logger(next http.Handler) http.Handler {
-	// simple logger setup
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		r = r.WithContext(logger.WithScope(r.Context()))
 		wrappedWriter := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(wrappedWriter, r)
 
-		logger.Info("Request Processed",
+		slog.InfoContext(r.Context(), "Request Processed",
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
 			slog.Int("status", wrappedWriter.status),
@@ -29,10 +30,28 @@ func Logger(next http.Handler) http.Handler {
 
 type responseWriter struct {
 	http.ResponseWriter
-	status int
+	status      int
+	wroteHeader bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
+	rw.wroteHeader = true
 	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers working, they type assert http.Flusher on what they get
+func (rw *responseWriter) Flush() {
+	rw.wroteHeader = true
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (Flush, deadlines)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
\ No newline at end of file
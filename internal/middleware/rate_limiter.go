@@ -47,7 +47,7 @@ func RateLimit(next http.Handler) http.Handler {
 		ip := r.RemoteAddr
 		// Basic IP extraction, in prod use X-Forwarded-For if behind proxy
 		if !limiter.GetLimiter(ip).Allow() {
-			response.Error(w, http.StatusTooManyRequests, "Too many requests")
+			response.Error(w, r, http.StatusTooManyRequests, "Too many requests")
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorWriter writes the response for a request that failed outside of its handler
+type ErrorWriter func(w http.ResponseWriter, r *http.Request, status int)
+
+// Recover turns a panicking handler into a logged 500 instead of a dropped connection.
+// writeError is skipped when the handler had already started its response.
+func Recover(writeError ErrorWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// Deliberate abort of the response, let net/http handle it
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				slog.ErrorContext(r.Context(), "Panic recovered",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Any("panic", rec),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if !rw.wroteHeader {
+					writeError(rw, r, http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// Unmatched serves mux and hands requests without a matching route to writeError,
+// as a 404 or, when only the method is wrong, a 405 with the Allow header set
+func Unmatched(mux *http.ServeMux, writeError ErrorWriter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(routeName(r.Method, pattern))
+			span.SetAttributes(attribute.String("http.route", pattern))
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Let the mux decide between 404 and 405, but keep only its status and Allow header
+		rec := &statusRecorder{header: http.Header{}, status: http.StatusNotFound}
+		handler.ServeHTTP(rec, r)
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+		}
+		writeError(w, r, rec.status)
+	})
+}
+
+// routeName names a span after the matched pattern, e.g. "GET /v1/users/{userId}"
+func routeName(method, pattern string) string {
+	if strings.HasPrefix(pattern, "/") {
+		return method + " " + pattern
+	}
+	return pattern
+}
+
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *statusRecorder) Header() http.Header         { return rec.header }
+func (rec *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (rec *statusRecorder) WriteHeader(code int)        { rec.status = code }
\ No newline at end of file
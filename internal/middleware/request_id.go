@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/telemetry"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+var tracer = telemetry.Tracer("http")
+
+// RequestID tags every request with an ID that is echoed in the X-Request-ID response header.
+// A well-formed incoming ID (e.g. from a proxy) is kept so both sides log the same value.
+// It also starts the server span, continuing the trace of an incoming W3C traceparent header,
+// and returns the span's own traceparent so callers can look the request up in their tracing.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("request_id", id),
+		))
+		defer span.End()
+
+		w.Header().Set(RequestIDHeader, id)
+		propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(telemetry.WithRequestID(ctx, id)))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", rw.status))
+		if rw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.status))
+		}
+	})
+}
+
+// GetRequestID returns the ID assigned by the RequestID middleware
+func GetRequestID(r *http.Request) string {
+	return telemetry.RequestID(r.Context())
+}
+
+// validRequestID only accepts short printable ASCII, the value ends up in logs and headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
\ No newline at end of file
@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 
+	"starter-kit-fullstack-gonethttp-template/internal/models"
 	"starter-kit-fullstack-gonethttp-template/internal/services"
 	"starter-kit-fullstack-gonethttp-template/pkg/response"
 
@@ -16,21 +17,21 @@ func RequireAdmin(service services.UserService) func(http.Handler) http.Handler
 			// 1. Get UserID from context (set by AuthJWT)
 			userIDStr, ok := r.Context().Value(UserIDKey).(string)
 			if !ok {
-				response.Error(w, http.StatusUnauthorized, "Unauthorized")
+				response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
 
 			// 2. Fetch User from DB
 			id, _ := uuid.Parse(userIDStr)
-			user, err := service.GetUserByID(id)
+			user, err := service.GetUserByID(r.Context(), id)
 			if err != nil {
-				response.Error(w, http.StatusUnauthorized, "User not found")
+				response.Error(w, r, http.StatusUnauthorized, "User not found")
 				return
 			}
 
 			// 3. Check Role
-			if user.Role != "admin" {
-				response.Error(w, http.StatusForbidden, "Forbidden: Admins only")
+			if user.Role != models.RoleAdmin {
+				response.Error(w, r, http.StatusForbidden, "Forbidden: Admins only")
 				return
 			}
 
@@ -47,7 +48,7 @@ func RequireAdminOrSelf(service services.UserService) func(http.Handler) http.Ha
 			// 1. Get UserID from context
 			userIDStr, ok := r.Context().Value(UserIDKey).(string)
 			if !ok {
-				response.Error(w, http.StatusUnauthorized, "Unauthorized")
+				response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
 
@@ -62,12 +63,46 @@ func RequireAdminOrSelf(service services.UserService) func(http.Handler) http.Ha
 
 			// 4. If not self, Check if Admin
 			id, _ := uuid.Parse(userIDStr)
-			user, err := service.GetUserByID(id)
-			if err != nil || user.Role != "admin" {
-				response.Error(w, http.StatusForbidden, "Forbidden: Access denied")
+			user, err := service.GetUserByID(r.Context(), id)
+			if err != nil || user.Role != models.RoleAdmin {
+				response.Error(w, r, http.StatusForbidden, "Forbidden: Access denied")
 				return
 			}
 
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSelf ensures the user is accessing their own resource, admins included.
+// For actions that would let an admin act as someone else, like creating their API keys.
+func RequireSelf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, ok := r.Context().Value(UserIDKey).(string)
+		if !ok {
+			response.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if r.PathValue("id") != userIDStr {
+			response.Error(w, r, http.StatusForbidden, "Forbidden: Access denied")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdminPage is RequireAdmin for pages behind AuthCookie, which already loaded the
+// user. Others get the error page from writeError.
+func RequireAdminPage(writeError ErrorWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := CurrentUser(r)
+			if !ok || user.Role != models.RoleAdmin {
+				writeError(w, r, http.StatusForbidden)
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
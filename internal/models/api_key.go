@@ -0,0 +1,51 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// API key scopes. A key can only reach endpoints whose required scopes it holds;
+// role checks (admin / self) still apply on top of that.
+const (
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+	ScopeAPIKeysManage = "apikeys:manage"
+)
+
+// APIKeyScopes lists every scope a key can be granted.
+var APIKeyScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeAPIKeysManage}
+
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"userId"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`        // First characters of the key, shown in listings
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"` // SHA-256 of the full key, never exposed
+	Scopes     []string   `gorm:"serializer:json;not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate generates a new UUID for the key
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return
+}
+
+// HasScope reports whether the key was granted the given scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// IsExpired reports whether the key has passed its expiry date
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
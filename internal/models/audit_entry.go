@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditEntry records a security relevant change to a user's account: sign-ins, password
+// and email changes, admin edits. Users get their entries with the data export.
+type AuditEntry struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"userId"`
+	Action    string    `gorm:"not null" json:"action"` // The event type, e.g. "auth.login"
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Details   []string  `gorm:"serializer:json" json:"details,omitempty"` // e.g. the fields an admin changed
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+// BeforeCreate generates a new UUID for the entry
+func (e *AuditEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
\ No newline at end of file
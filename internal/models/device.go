@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Device is a client a user has signed in from, used to spot sign-ins from new devices.
+// Fingerprint is a hash of the User-Agent; the IP is only kept for the notification email.
+type Device struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"userId"`
+	Fingerprint string    `gorm:"not null;index" json:"-"`
+	UserAgent   string    `json:"userAgent"`
+	LastIP      string    `json:"lastIp"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// BeforeCreate generates a new UUID for the device
+func (d *Device) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
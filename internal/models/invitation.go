@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invitation is an admin-issued invite for an email address that has no account yet.
+// The invitee picks their own name and password when accepting it.
+type Invitation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Email       string     `gorm:"index;not null" json:"email"`
+	Role        string     `gorm:"not null" json:"role"`
+	InvitedByID uuid.UUID  `gorm:"type:uuid;not null" json:"invitedById"`
+	Token       string     `gorm:"index;not null" json:"-"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expiresAt"`
+	AcceptedAt  *time.Time `json:"acceptedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate generates a new UUID for the invitation
+func (i *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
+
+// IsPending reports whether the invitation can still be accepted
+func (i *Invitation) IsPending() bool {
+	return i.AcceptedAt == nil && time.Now().Before(i.ExpiresAt)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSending = "sending" // Claimed by a worker
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed" // Dead-lettered after the last attempt, only an admin retry sends it again
+)
+
+// OutboxEmail is a rendered email waiting in (or done with) the outbound queue.
+// Bodies carry one-time links, so they are never serialized.
+type OutboxEmail struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	IdempotencyKey string     `gorm:"uniqueIndex;not null" json:"idempotencyKey"`
+	Template       string     `json:"template"`
+	To             string     `gorm:"not null" json:"to"`
+	Subject        string     `gorm:"not null" json:"subject"`
+	Text           string     `gorm:"not null" json:"-"`
+	HTML           string     `json:"-"`
+	Status         string     `gorm:"index;not null;default:pending" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time  `gorm:"index;not null" json:"nextAttemptAt"`
+	SentAt         *time.Time `json:"sentAt,omitempty"`
+	TraceParent    string     `json:"-"` // Trace of the request that queued the email, delivery continues it
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate generates a new UUID for the email
+func (e *OutboxEmail) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
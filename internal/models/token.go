@@ -8,6 +8,10 @@ const (
 	TokenTypeRefresh       = "refresh"
 	TokenTypeResetPassword = "resetPassword"
 	TokenTypeVerifyEmail   = "verifyEmail"
+	TokenTypeInvite        = "invite"        // Signed into Invitation.Token, the invitee has no user ID yet
+	TokenTypeSecureAccount = "secureAccount" // "This wasn't me" link in security emails
+	TokenTypeMagicLink     = "magicLink"     // Passwordless sign-in link, redeemable once
+	TokenTypePasskeyLogin  = "passkeyLogin"  // First factor passed, the passkey (second factor) is still due
 )
 
 type Token struct {
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,15 +9,40 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Roles lists every role a user can hold
+var Roles = []string{RoleUser, RoleAdmin}
+
+// IsValidRole reports whether role is one of the known Roles
+func IsValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+// NotificationPreferences controls which security emails a user receives
+type NotificationPreferences struct {
+	NewLogin       bool `gorm:"default:true" json:"newLogin"`       // Sign-in from a device not seen before
+	AccountChanges bool `gorm:"default:true" json:"accountChanges"` // Password resets/changes and email changes
+	AdminChanges   bool `gorm:"default:true" json:"adminChanges"`   // An admin edited the account
+}
+
 type User struct {
-	ID              uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Name            string    `gorm:"not null" json:"name"`
-	Email           string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password        string    `gorm:"not null" json:"-"` // Exclude from JSON output
-	Role            string    `gorm:"default:'user'" json:"role"`
-	IsEmailVerified bool      `gorm:"default:false" json:"isEmailVerified"`
-	CreatedAt       time.Time `json:"createdAt"`
-	UpdatedAt       time.Time `json:"updatedAt"`
+	ID                  uuid.UUID               `gorm:"type:uuid;primaryKey" json:"id"`
+	Name                string                  `gorm:"not null" json:"name"`
+	Email               string                  `gorm:"uniqueIndex;not null" json:"email"`
+	Password            string                  `gorm:"not null" json:"-"` // Exclude from JSON output
+	Role                string                  `gorm:"default:'user'" json:"role"`
+	IsEmailVerified     bool                    `gorm:"default:false" json:"isEmailVerified"`
+	PendingEmail        string                  `json:"pendingEmail,omitempty"`                     // New address awaiting verification
+	DeletionScheduledAt *time.Time              `gorm:"index" json:"deletionScheduledAt,omitempty"` // Self-deletion pending, signing in cancels it
+	Notifications       NotificationPreferences `gorm:"embedded;embeddedPrefix:notify_" json:"notifications"`
+	PasskeyRequired     bool                    `gorm:"default:false" json:"passkeyRequired"` // Password and magic-link sign-ins also need a passkey
+	Locale              string                  `gorm:"size:35" json:"locale"`                // Preferred language, e.g. "id" (empty = from the browser)
+	CreatedAt           time.Time               `json:"createdAt"`
+	UpdatedAt           time.Time               `json:"updatedAt"`
 }
 
 // BeforeCreate generates a new UUID for the user
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebAuthnCredential is a passkey registered by a user.
+// SignCount is the last counter reported by the authenticator; a counter that stops
+// increasing means the key may have been cloned, so the credential is flagged and refused.
+type WebAuthnCredential struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"userId"`
+	Name            string     `gorm:"not null" json:"name"`
+	CredentialID    []byte     `gorm:"uniqueIndex;not null" json:"-"`
+	PublicKey       []byte     `gorm:"not null" json:"-"` // COSE encoded
+	AttestationType string     `json:"attestationType"`
+	AAGUID          []byte     `json:"-"`
+	Transports      []string   `gorm:"serializer:json" json:"transports"`
+	Flags           uint8      `json:"-"`        // Raw authenticator flags, backup eligibility must never change
+	BackedUp        bool       `json:"backedUp"` // Synced passkey (e.g. iCloud Keychain, Google Password Manager)
+	SignCount       uint32     `json:"signCount"`
+	CloneWarning    bool       `json:"cloneWarning"`
+	LastUsedAt      *time.Time `json:"lastUsedAt"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// BeforeCreate generates a new UUID for the credential
+func (c *WebAuthnCredential) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+// WebAuthn ceremonies
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+)
+
+// WebAuthnSession keeps the challenge between the begin and finish steps of a ceremony.
+// It is deleted when the ceremony finishes, so a signed challenge can't be replayed.
+type WebAuthnSession struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"` // Nil for passwordless (discoverable) sign-in
+	Ceremony  string     `gorm:"not null"`
+	Data      []byte     `gorm:"not null"` // JSON encoded webauthn.SessionData
+	ExpiresAt time.Time  `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+func (WebAuthnSession) TableName() string {
+	return "webauthn_sessions"
+}
+
+// BeforeCreate generates a new UUID for the session
+func (s *WebAuthnSession) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
\ No newline at end of file
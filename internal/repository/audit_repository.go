@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db}
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *auditRepository) FindAllByUserID(ctx context.Context, userID uuid.UUID) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at desc").Find(&entries).Error
+	return entries, err
+}
+
+func (r *auditRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.AuditEntry{}).Error
+}
\ No newline at end of file
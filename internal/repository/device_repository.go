@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return &deviceRepository{db}
+}
+
+func (r *deviceRepository) Create(ctx context.Context, device *models.Device) error {
+	return r.db.WithContext(ctx).Create(device).Error
+}
+
+func (r *deviceRepository) FindByFingerprint(ctx context.Context, userID uuid.UUID, fingerprint string) (*models.Device, error) {
+	var device models.Device
+	err := r.db.WithContext(ctx).Where("user_id = ? AND fingerprint = ?", userID, fingerprint).First(&device).Error
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *deviceRepository) FindAllByUserID(ctx context.Context, userID uuid.UUID) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_seen_at desc").Find(&devices).Error
+	return devices, err
+}
+
+func (r *deviceRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Device{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *deviceRepository) FindSeenBetween(ctx context.Context, from, to time.Time, limit int) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.WithContext(ctx).Where("last_seen_at >= ? AND last_seen_at < ?", from, to).Order("last_seen_at desc").Limit(limit).Find(&devices).Error
+	return devices, err
+}
+
+func (r *deviceRepository) CountUsersSeenBetween(ctx context.Context, from, to time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Device{}).Where("last_seen_at >= ? AND last_seen_at < ?", from, to).Distinct("user_id").Count(&count).Error
+	return count, err
+}
+
+func (r *deviceRepository) Update(ctx context.Context, device *models.Device) error {
+	return r.db.WithContext(ctx).Save(device).Error
+}
+
+func (r *deviceRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Device{}).Error
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db}
+}
+
+func (r *invitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *invitationRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) FindByToken(ctx context.Context, token string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindOpen returns invitations that were not accepted yet, including expired ones so they can be resent
+func (r *invitationRepository) FindOpen(ctx context.Context) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.WithContext(ctx).Where("accepted_at IS NULL").Order("created_at desc").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *invitationRepository) FindOpenByEmail(ctx context.Context, email string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.WithContext(ctx).Where("email = ? AND accepted_at IS NULL", email).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) Update(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Save(invitation).Error
+}
+
+func (r *invitationRepository) Delete(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Delete(invitation).Error
+}
+
+// DeleteOpenByInviter withdraws the invitations inviterID sent that were not accepted yet
+func (r *invitationRepository) DeleteOpenByInviter(ctx context.Context, inviterID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("invited_by_id = ? AND accepted_at IS NULL", inviterID).Delete(&models.Invitation{}).Error
+}
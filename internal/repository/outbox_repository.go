@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db}
+}
+
+// Create inserts the email unless one with the same idempotency key exists,
+// in which case email is replaced by the stored row and created is false
+func (r *outboxRepository) Create(ctx context.Context, email *models.OutboxEmail) (bool, error) {
+	existing, err := r.FindByIdempotencyKey(ctx, email.IdempotencyKey)
+	if err == nil {
+		*email = *existing
+		return false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	return true, r.db.WithContext(ctx).Create(email).Error
+}
+
+func (r *outboxRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&email).Error
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+func (r *outboxRepository) FindByIdempotencyKey(ctx context.Context, key string) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	err := r.db.WithContext(ctx).Where("idempotency_key = ?", key).First(&email).Error
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+func (r *outboxRepository) FindAll(ctx context.Context, status string, pagination *utils.PaginationScope) ([]models.OutboxEmail, int64, error) {
+	var emails []models.OutboxEmail
+	var totalRows int64
+
+	query := r.db.WithContext(ctx).Model(&models.OutboxEmail{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&totalRows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at desc").Scopes(pagination.Paginate()).Find(&emails).Error
+	return emails, totalRows, err
+}
+
+// ClaimDue marks up to limit due emails as sending and returns them.
+// The status check in the UPDATE keeps two instances from claiming the same row.
+func (r *outboxRepository) ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.OutboxEmail, error) {
+	var due []models.OutboxEmail
+	err := r.db.WithContext(ctx).Where("status = ? AND next_attempt_at <= ?", models.EmailStatusPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := due[:0]
+	for _, email := range due {
+		res := r.db.WithContext(ctx).Model(&models.OutboxEmail{}).
+			Where("id = ? AND status = ?", email.ID, models.EmailStatusPending).
+			Update("status", models.EmailStatusSending)
+		if res.Error != nil {
+			return claimed, res.Error
+		}
+		if res.RowsAffected == 1 {
+			email.Status = models.EmailStatusSending
+			claimed = append(claimed, email)
+		}
+	}
+	return claimed, nil
+}
+
+// ReleaseStale puts emails that were left in sending (e.g. after a crash) back in the queue
+func (r *outboxRepository) ReleaseStale(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&models.OutboxEmail{}).
+		Where("status = ? AND updated_at < ?", models.EmailStatusSending, before).
+		Update("status", models.EmailStatusPending)
+	return res.RowsAffected, res.Error
+}
+
+func (r *outboxRepository) Update(ctx context.Context, email *models.OutboxEmail) error {
+	return r.db.WithContext(ctx).Save(email).Error
+}
@@ -1,27 +1,116 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"starter-kit-fullstack-gonethttp-template/internal/models"
 	"starter-kit-fullstack-gonethttp-template/pkg/utils"
 
 	"github.com/google/uuid"
 )
 
+// BucketCount is the number of rows in the day, week (starting Monday) or month
+// starting at Start, in UTC
+type BucketCount struct {
+	Start time.Time
+	Count int64
+}
+
 type UserRepository interface {
-	Create(user *models.User) error
-	FindByEmail(email string) (*models.User, error)
-	FindByID(id uuid.UUID) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	// FindAll handles searching, filtering, and pagination
-	FindAll(filters map[string]interface{}, search string, searchFields []string, pagination *utils.PaginationScope) ([]models.User, int64, error)
-	ExistsByEmail(email string) (bool, error)
-	Update(user *models.User) error
-	Delete(id uuid.UUID) error
+	FindAll(ctx context.Context, filters map[string]interface{}, search string, searchFields []string, pagination *utils.PaginationScope) ([]models.User, int64, error)
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	// CountByRole leaves out users scheduled for deletion
+	CountByRole(ctx context.Context, role string) (int64, error)
+	CountGroupedByRole(ctx context.Context) (map[string]int64, error)
+	CountVerified(ctx context.Context) (int64, error)
+	// CountCreatedPerBucket counts the sign-ups in [from, to) per day, week or month
+	CountCreatedPerBucket(ctx context.Context, from, to time.Time, interval string) ([]BucketCount, error)
+	FindByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error)
+	FindScheduledForDeletion(ctx context.Context, before time.Time) ([]models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	// UpdateUnlessLastAdmin saves a change that stops user counting as an active admin, in the
+	// transaction that checks another one remains; it reports false without saving otherwise
+	UpdateUnlessLastAdmin(ctx context.Context, user *models.User) (bool, error)
+	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 type TokenRepository interface {
-	Create(token *models.Token) error
-	FindByToken(token string, tokenType string) (*models.Token, error)
-	DeleteByUserIDAndType(userID string, tokenType string) error
-	Delete(token *models.Token) error
-	DeleteByUserID(userID string) error
-}
\ No newline at end of file
+	Create(ctx context.Context, token *models.Token) error
+	FindByToken(ctx context.Context, token string, tokenType string) (*models.Token, error)
+	FindAllByUserIDAndType(ctx context.Context, userID string, tokenType string) ([]models.Token, error)
+	DeleteByUserIDAndType(ctx context.Context, userID string, tokenType string) error
+	Delete(ctx context.Context, token *models.Token) error
+	// Consume deletes the token and reports whether this call removed it, so it can only be redeemed once
+	Consume(ctx context.Context, token *models.Token) (bool, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+	// CountActive counts the unexpired, not blacklisted tokens of a type and the users holding them
+	CountActive(ctx context.Context, tokenType string, now time.Time) (tokens int64, users int64, err error)
+}
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *models.Invitation) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Invitation, error)
+	FindByToken(ctx context.Context, token string) (*models.Invitation, error)
+	FindOpen(ctx context.Context) ([]models.Invitation, error)
+	FindOpenByEmail(ctx context.Context, email string) (*models.Invitation, error)
+	Update(ctx context.Context, invitation *models.Invitation) error
+	Delete(ctx context.Context, invitation *models.Invitation) error
+	DeleteOpenByInviter(ctx context.Context, inviterID uuid.UUID) error
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	FindByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	FindByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*models.APIKey, error)
+	FindAllByUserID(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+	Delete(ctx context.Context, key *models.APIKey) error
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+type OutboxRepository interface {
+	// Create returns false when an email with the same idempotency key was already queued
+	Create(ctx context.Context, email *models.OutboxEmail) (bool, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error)
+	FindByIdempotencyKey(ctx context.Context, key string) (*models.OutboxEmail, error)
+	FindAll(ctx context.Context, status string, pagination *utils.PaginationScope) ([]models.OutboxEmail, int64, error)
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.OutboxEmail, error)
+	ReleaseStale(ctx context.Context, before time.Time) (int64, error)
+	Update(ctx context.Context, email *models.OutboxEmail) error
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	// FindAllByUserID returns the entries of a user, latest first
+	FindAllByUserID(ctx context.Context, userID uuid.UUID) ([]models.AuditEntry, error)
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+type DeviceRepository interface {
+	Create(ctx context.Context, device *models.Device) error
+	FindByFingerprint(ctx context.Context, userID uuid.UUID, fingerprint string) (*models.Device, error)
+	FindAllByUserID(ctx context.Context, userID uuid.UUID) ([]models.Device, error)
+	CountByUserID(ctx context.Context, userID uuid.UUID) (int64, error)
+	// FindSeenBetween returns the devices last signed in from within [from, to), latest first
+	FindSeenBetween(ctx context.Context, from, to time.Time, limit int) ([]models.Device, error)
+	CountUsersSeenBetween(ctx context.Context, from, to time.Time) (int64, error)
+	Update(ctx context.Context, device *models.Device) error
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+type WebAuthnRepository interface {
+	CreateCredential(ctx context.Context, credential *models.WebAuthnCredential) error
+	FindCredentialByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*models.WebAuthnCredential, error)
+	FindCredentialsByUserID(ctx context.Context, userID uuid.UUID) ([]models.WebAuthnCredential, error)
+	CountCredentialsByUserID(ctx context.Context, userID uuid.UUID) (int64, error)
+	UpdateCredential(ctx context.Context, credential *models.WebAuthnCredential) error
+	DeleteCredential(ctx context.Context, credential *models.WebAuthnCredential) error
+	DeleteCredentialsByUserID(ctx context.Context, userID uuid.UUID) error
+	CreateSession(ctx context.Context, session *models.WebAuthnSession) error
+	// TakeSession returns an unexpired session and deletes it, so each challenge is answered once
+	TakeSession(ctx context.Context, id uuid.UUID, ceremony string) (*models.WebAuthnSession, error)
+}
@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"starter-kit-fullstack-gonethttp-template/internal/models"
 
 	"gorm.io/gorm"
@@ -14,27 +17,51 @@ func NewTokenRepository(db *gorm.DB) TokenRepository {
 	return &tokenRepository{db}
 }
 
-func (r *tokenRepository) Create(token *models.Token) error {
-	return r.db.Create(token).Error
+func (r *tokenRepository) Create(ctx context.Context, token *models.Token) error {
+	return r.db.WithContext(ctx).Create(token).Error
 }
 
-func (r *tokenRepository) FindByToken(tokenStr string, tokenType string) (*models.Token, error) {
+func (r *tokenRepository) FindByToken(ctx context.Context, tokenStr string, tokenType string) (*models.Token, error) {
 	var token models.Token
-	err := r.db.Where("token = ? AND type = ? AND blacklisted = ?", tokenStr, tokenType, false).First(&token).Error
+	err := r.db.WithContext(ctx).Where("token = ? AND type = ? AND blacklisted = ?", tokenStr, tokenType, false).First(&token).Error
 	if err != nil {
 		return nil, err
 	}
 	return &token, nil
 }
 
-func (r *tokenRepository) DeleteByUserIDAndType(userID string, tokenType string) error {
-	return r.db.Where("user_id = ? AND type = ?", userID, tokenType).Delete(&models.Token{}).Error
+func (r *tokenRepository) FindAllByUserIDAndType(ctx context.Context, userID string, tokenType string) ([]models.Token, error) {
+	var tokens []models.Token
+	err := r.db.WithContext(ctx).Where("user_id = ? AND type = ?", userID, tokenType).Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *tokenRepository) DeleteByUserIDAndType(ctx context.Context, userID string, tokenType string) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND type = ?", userID, tokenType).Delete(&models.Token{}).Error
+}
+
+func (r *tokenRepository) Delete(ctx context.Context, token *models.Token) error {
+	return r.db.WithContext(ctx).Delete(token).Error
 }
 
-func (r *tokenRepository) Delete(token *models.Token) error {
-	return r.db.Delete(token).Error
+func (r *tokenRepository) Consume(ctx context.Context, token *models.Token) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(token)
+	return result.RowsAffected == 1, result.Error
 }
 
-func (r *tokenRepository) DeleteByUserID(userID string) error {
-	return r.db.Where("user_id = ?", userID).Delete(&models.Token{}).Error
+func (r *tokenRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Token{}).Error
+}
+
+func (r *tokenRepository) CountActive(ctx context.Context, tokenType string, now time.Time) (int64, int64, error) {
+	active := r.db.WithContext(ctx).Model(&models.Token{}).Where("type = ? AND blacklisted = ? AND expires > ?", tokenType, false, now)
+
+	var tokens, users int64
+	if err := active.Session(&gorm.Session{}).Count(&tokens).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := active.Session(&gorm.Session{}).Distinct("user_id").Count(&users).Error; err != nil {
+		return 0, 0, err
+	}
+	return tokens, users, nil
 }
\ No newline at end of file
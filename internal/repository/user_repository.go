@@ -1,14 +1,18 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"starter-kit-fullstack-gonethttp-template/internal/models"
 	"starter-kit-fullstack-gonethttp-template/pkg/utils"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -19,27 +23,27 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db}
 }
 
-func (r *userRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	return &user, err
 }
 
-func (r *userRepository) FindByID(id uuid.UUID) (*models.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("id = ?", id).First(&user).Error
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
 	return &user, err
 }
 
-func (r *userRepository) FindAll(filters map[string]interface{}, search string, searchFields []string, pagination *utils.PaginationScope) ([]models.User, int64, error) {
+func (r *userRepository) FindAll(ctx context.Context, filters map[string]interface{}, search string, searchFields []string, pagination *utils.PaginationScope) ([]models.User, int64, error) {
 	var users []models.User
 	var totalRows int64
 
-	query := r.db.Model(&models.User{})
+	query := r.db.WithContext(ctx).Model(&models.User{})
 
 	// 1. Apply Strict Filters (e.g. Role)
 	for key, value := range filters {
@@ -55,7 +59,7 @@ func (r *userRepository) FindAll(filters map[string]interface{}, search string,
 
 		for _, field := range searchFields {
 			if field == "name" || field == "email" || field == "role" || field == "id" {
-				if r.db.Dialector.Name() == "postgres" && field == "id" {
+				if r.db.WithContext(ctx).Dialector.Name() == "postgres" && field == "id" {
 					searchConditions = append(searchConditions, "CAST(id AS TEXT) LIKE ?")
 				} else {
 					searchConditions = append(searchConditions, fmt.Sprintf("%s LIKE ?", field))
@@ -90,16 +94,141 @@ func (r *userRepository) FindAll(filters map[string]interface{}, search string,
 	return users, totalRows, err
 }
 
-func (r *userRepository) ExistsByEmail(email string) (bool, error) {
+func (r *userRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).Count(&count).Error
 	return count > 0, err
 }
 
-func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("role = ? AND deletion_scheduled_at IS NULL", role).Count(&count).Error
+	return count, err
+}
+
+func (r *userRepository) CountGroupedByRole(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Role  string
+		Count int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.User{}).Select("role, count(*) as count").Group("role").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *userRepository) CountVerified(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("is_email_verified = ?", true).Count(&count).Error
+	return count, err
+}
+
+func (r *userRepository) CountCreatedPerBucket(ctx context.Context, from, to time.Time, interval string) ([]BucketCount, error) {
+	db := r.db.WithContext(ctx)
+	bucket, err := dateBucket(db.Dialector.Name(), "created_at", interval)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Bucket string
+		Count  int64
+	}
+	err = db.Model(&models.User{}).
+		Select(bucket+" AS bucket, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("bucket").
+		Order("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]BucketCount, 0, len(rows))
+	for _, row := range rows {
+		start, err := time.Parse(time.DateOnly, row.Bucket)
+		if err != nil {
+			return nil, fmt.Errorf("date bucket %q: %w", row.Bucket, err)
+		}
+		counts = append(counts, BucketCount{Start: start, Count: row.Count})
+	}
+	return counts, nil
+}
+
+// dateBucket returns the SQL that truncates column to the UTC date starting its day,
+// week (on Monday) or month, as "2006-01-02"
+func dateBucket(dialect, column, interval string) (string, error) {
+	switch dialect {
+	case "postgres":
+		// date_trunc weeks are ISO weeks, which start on Monday
+		switch interval {
+		case "day", "week", "month":
+			return fmt.Sprintf("to_char(date_trunc('%s', %s AT TIME ZONE 'UTC'), 'YYYY-MM-DD')", interval, column), nil
+		}
+	case "sqlite":
+		// Dates carry their offset, SQLite's date functions convert them to UTC
+		switch interval {
+		case "day":
+			return fmt.Sprintf("date(%s)", column), nil
+		case "week":
+			// The coming Sunday (or the day itself), then back to its Monday
+			return fmt.Sprintf("date(%s, 'weekday 0', '-6 days')", column), nil
+		case "month":
+			return fmt.Sprintf("strftime('%%Y-%%m-01', %s)", column), nil
+		}
+	default:
+		return "", fmt.Errorf("date buckets are not supported on %s", dialect)
+	}
+	return "", fmt.Errorf("unknown bucket interval %q", interval)
+}
+
+func (r *userRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) FindScheduledForDeletion(ctx context.Context, before time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", before).Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func (r *userRepository) UpdateUnlessLastAdmin(ctx context.Context, user *models.User) (bool, error) {
+	saved := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking every active admin makes concurrent changes to them wait for this one,
+		// so two admins can't each step down counting on the other
+		var adminIDs []uuid.UUID
+		err := tx.Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role = ? AND deletion_scheduled_at IS NULL", models.RoleAdmin).
+			Pluck("id", &adminIDs).Error
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(adminIDs, func(id uuid.UUID) bool { return id != user.ID }) {
+			return nil
+		}
+
+		saved = true
+		return tx.Save(user).Error
+	})
+	return saved && err == nil, err
 }
 
-func (r *userRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.User{}, id).Error
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
 }
\ No newline at end of file
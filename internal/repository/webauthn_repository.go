@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type webAuthnRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnRepository(db *gorm.DB) WebAuthnRepository {
+	return &webAuthnRepository{db}
+}
+
+func (r *webAuthnRepository) CreateCredential(ctx context.Context, credential *models.WebAuthnCredential) error {
+	return r.db.WithContext(ctx).Create(credential).Error
+}
+
+func (r *webAuthnRepository) FindCredentialByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&credential).Error
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r *webAuthnRepository) FindCredentialsByUserID(ctx context.Context, userID uuid.UUID) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at asc").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *webAuthnRepository) CountCredentialsByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *webAuthnRepository) UpdateCredential(ctx context.Context, credential *models.WebAuthnCredential) error {
+	return r.db.WithContext(ctx).Save(credential).Error
+}
+
+func (r *webAuthnRepository) DeleteCredential(ctx context.Context, credential *models.WebAuthnCredential) error {
+	return r.db.WithContext(ctx).Delete(credential).Error
+}
+
+func (r *webAuthnRepository) DeleteCredentialsByUserID(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.WebAuthnCredential{}).Error
+}
+
+func (r *webAuthnRepository) CreateSession(ctx context.Context, session *models.WebAuthnSession) error {
+	// Abandoned ceremonies are cleaned up whenever a new one starts
+	if err := r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&models.WebAuthnSession{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *webAuthnRepository) TakeSession(ctx context.Context, id uuid.UUID, ceremony string) (*models.WebAuthnSession, error) {
+	var session models.WebAuthnSession
+	err := r.db.WithContext(ctx).Where("id = ? AND ceremony = ? AND expires_at > ?", id, ceremony, time.Now()).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the request that deletes the session may finish the ceremony
+	result := r.db.WithContext(ctx).Delete(&session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, nil
+}
\ No newline at end of file
@@ -2,12 +2,17 @@ package routes
 
 import (
 	"net/http"
+	"strings"
 
 	"starter-kit-fullstack-gonethttp-template/config"
 	apiHandlers "starter-kit-fullstack-gonethttp-template/internal/handlers/api"
 	webHandlers "starter-kit-fullstack-gonethttp-template/internal/handlers/web"
 	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
 	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/assets"
+	"starter-kit-fullstack-gonethttp-template/pkg/response"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
 
 	// Swagger Docs dependency
 	_ "starter-kit-fullstack-gonethttp-template/docs"
@@ -15,14 +20,28 @@ import (
 )
 
 type Handlers struct {
-	APIAuth *apiHandlers.AuthHandler
-	APIUser *apiHandlers.UserHandler
-	WebAuth *webHandlers.AuthHandler
-	WebUser *webHandlers.UserHandler
-	WebDash *webHandlers.DashboardHandler
+	APIAuth    *apiHandlers.AuthHandler
+	APIUser    *apiHandlers.UserHandler
+	APIAPIKey  *apiHandlers.APIKeyHandler
+	APIMe      *apiHandlers.ProfileHandler
+	APIInvite  *apiHandlers.InvitationHandler
+	APIOutbox  *apiHandlers.OutboxHandler
+	APINotify  *apiHandlers.NotificationHandler
+	APIPasskey *apiHandlers.WebAuthnHandler
+	APIStats   *apiHandlers.StatsHandler
+	WebAuth    *webHandlers.AuthHandler
+	WebUser    *webHandlers.UserHandler
+	WebDash    *webHandlers.DashboardHandler
+	WebAPIKey  *webHandlers.APIKeyHandler
+	WebMe      *webHandlers.ProfileHandler
+	WebInvite  *webHandlers.InvitationHandler
+	WebEmails  *webHandlers.EmailPreviewHandler
+	WebOutbox  *webHandlers.OutboxHandler
+	WebSecure  *webHandlers.SecureAccountHandler
+	WebInbox   *webHandlers.InboxHandler // Only set with EMAIL_TRANSPORT=memory
 }
 
-func RegisterRoutes(cfg *config.Config, h Handlers, userService services.UserService) http.Handler {
+func RegisterRoutes(cfg *config.Config, h Handlers, userService services.UserService, apiKeyService services.APIKeyService, authService services.AuthService, static *assets.Server) http.Handler {
 	mux := http.NewServeMux()
 
 	// Middleware Definitions
@@ -30,19 +49,53 @@ func RegisterRoutes(cfg *config.Config, h Handlers, userService services.UserSer
 	security := middleware.SecurityHeaders
 	rateLimit := middleware.RateLimit
 	csrf := middleware.CSRF
+	requestID := middleware.RequestID
+	locale := middleware.Locale
+	compress := middleware.Compress(1024, middleware.CompressibleTypes) // Smaller bodies fit in a packet anyway
 
-	// Auth Middleware
-	authJWT := middleware.AuthJWT(cfg, []string{})
+	// Failures outside the handlers (panics, unknown routes): problem+json for API clients,
+	// the error pages for browsers
+	writeError := func(w http.ResponseWriter, r *http.Request, status int) {
+		if strings.HasPrefix(r.URL.Path, "/v1/") || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			response.Error(w, r, status, "")
+			return
+		}
+		view.RenderError(w, r, status)
+	}
+	recoverer := middleware.Recover(writeError)
+
+	// Auth Middleware (Bearer JWT, API key or the session cookies; scopes only restrict API keys)
+	authJWT := func(scopes ...string) func(http.Handler) http.Handler {
+		return middleware.AuthJWT(cfg, userService, apiKeyService, authService, scopes)
+	}
+	rejectAPIKey := middleware.RejectAPIKey // Credential changes need the user, not a key
 	
+	// Body Limits (other routes get request.DefaultMaxBodyBytes when decoding JSON)
+	authBody := middleware.MaxBodySize(16 << 10)    // Public auth endpoints, a few small fields
+	passkeyBody := middleware.MaxBodySize(64 << 10) // WebAuthn responses carry attestation data
+
 	// Role Middleware
 	requireAdmin := middleware.RequireAdmin(userService)
 	requireAdminOrSelf := middleware.RequireAdminOrSelf(userService)
+	requireSelf := middleware.RequireSelf
+
+	// Pages of signed in users, through the session cookies. Guests are sent to the login
+	// page and signed in users past it.
+	page := func(handler http.HandlerFunc) http.Handler {
+		return middleware.AuthCookie(cfg, userService, authService)(handler)
+	}
+	adminPage := func(handler http.HandlerFunc) http.Handler {
+		return middleware.AuthCookie(cfg, userService, authService)(middleware.RequireAdminPage(writeError)(handler))
+	}
+	guestPage := func(handler http.HandlerFunc) http.Handler {
+		return middleware.GuestOnly(cfg, authService)(handler)
+	}
 
 	// ---------------------------
 	// 1. Static Files
 	// ---------------------------
-	fs := http.FileServer(http.Dir("./web/static"))
-	mux.Handle("GET /assets/", http.StripPrefix("/assets/", fs))
+	// Fingerprinted, precompressed and cached, see view's asset function
+	mux.Handle("GET /assets/", http.StripPrefix("/assets/", static))
 
 	// ---------------------------
 	// 2. Swagger Documentation
@@ -54,62 +107,150 @@ func RegisterRoutes(cfg *config.Config, h Handlers, userService services.UserSer
 	// ---------------------------
 	// 3. Web Routes (HTML)
 	// ---------------------------
-	mux.HandleFunc("GET /login", h.WebAuth.ViewLogin)
-	mux.HandleFunc("GET /register", h.WebAuth.ViewRegister)
+	mux.Handle("GET /login", guestPage(h.WebAuth.ViewLogin))
+	mux.Handle("POST /login", authBody(http.HandlerFunc(h.WebAuth.Login)))
+	mux.HandleFunc("POST /logout", h.WebAuth.Logout)
+	mux.Handle("GET /register", guestPage(h.WebAuth.ViewRegister))
+	mux.Handle("POST /register", authBody(http.HandlerFunc(h.WebAuth.Register)))
 	mux.HandleFunc("GET /forgot-password", h.WebAuth.ViewForgotPassword)
+	mux.Handle("POST /forgot-password", authBody(http.HandlerFunc(h.WebAuth.ForgotPassword)))
+	mux.HandleFunc("GET /reset-password", h.WebAuth.ViewResetPassword)
+	mux.Handle("POST /reset-password", authBody(http.HandlerFunc(h.WebAuth.ResetPassword)))
+	mux.HandleFunc("GET /verify-email", h.WebAuth.ViewVerifyEmail)
+	mux.Handle("POST /verify-email", authBody(http.HandlerFunc(h.WebAuth.VerifyEmail)))
+	mux.HandleFunc("GET /accept-invite", h.WebInvite.ViewAcceptInvite)
+	mux.HandleFunc("GET /secure-account", h.WebSecure.ViewSecureAccount)
 
-	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			http.NotFound(w, r)
+	// Magic link: browsers get a confirmation page, which (like API clients) exchanges the token as JSON
+	mux.HandleFunc("GET /auth/magic", func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			h.APIAuth.MagicLogin(w, r)
 			return
 		}
-		h.WebDash.Index(w, r)
+		h.WebAuth.ViewMagicLink(w, r)
 	})
 
-	// Web User Management (View Only - API handles logic)
-	mux.HandleFunc("GET /users", h.WebUser.Index)
-	mux.HandleFunc("GET /users/create", h.WebUser.CreateView)
-	mux.HandleFunc("GET /users/edit", h.WebUser.EditView)
+	// The statistics widgets are for admins only
+	mux.Handle("GET /{$}", page(h.WebDash.Index))
+
+	// Web User Management (rendered and saved on the server through UserService)
+	mux.Handle("GET /users", adminPage(h.WebUser.Index))
+	mux.Handle("GET /users/create", adminPage(h.WebUser.CreateView))
+	mux.Handle("POST /users/create", adminPage(h.WebUser.Create))
+	mux.Handle("GET /users/{id}/edit", adminPage(h.WebUser.EditView))
+	mux.Handle("POST /users/{id}/edit", adminPage(h.WebUser.Update))
+	mux.Handle("POST /users/{id}/delete", adminPage(h.WebUser.Delete))
+	mux.Handle("GET /users/invitations", adminPage(h.WebInvite.Index))
+	mux.Handle("GET /api-keys", page(h.WebAPIKey.Index))
+	mux.Handle("GET /profile", page(h.WebMe.Index))
+	mux.Handle("GET /admin/emails", adminPage(h.WebOutbox.Index))
+
+	// Email template previews (Development only)
+	if cfg.App.Env == "development" {
+		mux.HandleFunc("GET /dev/emails", h.WebEmails.Index)
+		mux.HandleFunc("GET /dev/emails/{name}", h.WebEmails.Show)
+	}
+
+	// Captured emails (Memory transport, Development only)
+	if h.WebInbox != nil && cfg.App.Env == "development" {
+		mux.HandleFunc("GET /dev/inbox", h.WebInbox.Index)
+		mux.HandleFunc("GET /dev/inbox/messages", h.WebInbox.Messages)
+		mux.HandleFunc("GET /dev/inbox/{id}", h.WebInbox.Show)
+		mux.HandleFunc("POST /dev/inbox/clear", h.WebInbox.Clear)
+	}
 
 	// ---------------------------
 	// 4. API Routes (JSON)
 	// ---------------------------
 
 	// Public API
-	mux.HandleFunc("POST /v1/auth/register", h.APIAuth.Register)
-	mux.HandleFunc("POST /v1/auth/login", h.APIAuth.Login)
-	mux.HandleFunc("POST /v1/auth/logout", h.APIAuth.Logout)
-	mux.HandleFunc("POST /v1/auth/refresh-tokens", h.APIAuth.RefreshTokens)
-	mux.HandleFunc("POST /v1/auth/forgot-password", h.APIAuth.ForgotPassword)
-	mux.HandleFunc("POST /v1/auth/reset-password", h.APIAuth.ResetPassword)
+	mux.Handle("POST /v1/auth/register", authBody(http.HandlerFunc(h.APIAuth.Register)))
+	mux.Handle("POST /v1/auth/login", authBody(http.HandlerFunc(h.APIAuth.Login)))
+	mux.Handle("POST /v1/auth/logout", authBody(http.HandlerFunc(h.APIAuth.Logout)))
+	mux.Handle("POST /v1/auth/refresh-tokens", authBody(http.HandlerFunc(h.APIAuth.RefreshTokens)))
+	mux.Handle("POST /v1/auth/forgot-password", authBody(http.HandlerFunc(h.APIAuth.ForgotPassword)))
+	mux.Handle("POST /v1/auth/magic-link", authBody(http.HandlerFunc(h.APIAuth.MagicLink)))
+	mux.Handle("POST /v1/auth/reset-password", authBody(http.HandlerFunc(h.APIAuth.ResetPassword)))
+	mux.Handle("POST /v1/auth/verify-email", authBody(http.HandlerFunc(h.APIAuth.VerifyEmail)))
+	mux.Handle("POST /v1/auth/accept-invite", authBody(http.HandlerFunc(h.APIInvite.AcceptInvitation)))
+	mux.Handle("POST /v1/auth/secure-account", authBody(http.HandlerFunc(h.APINotify.SecureAccount)))
+	mux.Handle("POST /v1/auth/webauthn/login/begin", authBody(http.HandlerFunc(h.APIPasskey.BeginLogin)))
+	mux.Handle("POST /v1/auth/webauthn/login/finish", passkeyBody(http.HandlerFunc(h.APIPasskey.FinishLogin)))
 
 	// Protected API (Requires Bearer Token)
+
+	// /me -> Self-service for the authenticated user
+	mux.Handle("GET /v1/me", authJWT(models.ScopeUsersRead)(http.HandlerFunc(h.APIMe.GetProfile)))
+	mux.Handle("PATCH /v1/me", authJWT(models.ScopeUsersWrite)(http.HandlerFunc(h.APIMe.UpdateProfile)))
+	mux.Handle("POST /v1/me/password", authJWT(models.ScopeUsersWrite)(http.HandlerFunc(h.APIMe.ChangePassword)))
+	mux.Handle("POST /v1/me/email", authJWT(models.ScopeUsersWrite)(http.HandlerFunc(h.APIMe.ChangeEmail)))
+	mux.Handle("PATCH /v1/me/notifications", authJWT(models.ScopeUsersWrite)(http.HandlerFunc(h.APINotify.UpdateNotifications)))
+	mux.Handle("GET /v1/me/export", authJWT(models.ScopeUsersRead)(http.HandlerFunc(h.APIMe.ExportData)))
+	mux.Handle("DELETE /v1/me", authJWT(models.ScopeUsersWrite)(http.HandlerFunc(h.APIMe.DeleteAccount)))
+
+	// Passkeys (registration needs a signed in user; adding, removing or no longer requiring
+	// one takes the user's token or session, never an API key)
+	mux.Handle("POST /v1/auth/webauthn/register/begin", authJWT(models.ScopeUsersWrite)(rejectAPIKey(http.HandlerFunc(h.APIPasskey.BeginRegistration))))
+	mux.Handle("POST /v1/auth/webauthn/register/finish", authJWT(models.ScopeUsersWrite)(rejectAPIKey(passkeyBody(http.HandlerFunc(h.APIPasskey.FinishRegistration)))))
+	mux.Handle("GET /v1/me/passkeys", authJWT(models.ScopeUsersRead)(http.HandlerFunc(h.APIPasskey.GetPasskeys)))
+	mux.Handle("PUT /v1/me/passkeys/required", authJWT(models.ScopeUsersWrite)(rejectAPIKey(http.HandlerFunc(h.APIPasskey.SetPasskeyRequired))))
+	mux.Handle("PATCH /v1/me/passkeys/{id}", authJWT(models.ScopeUsersWrite)(http.HandlerFunc(h.APIPasskey.RenamePasskey)))
+	mux.Handle("DELETE /v1/me/passkeys/{id}", authJWT(models.ScopeUsersWrite)(rejectAPIKey(http.HandlerFunc(h.APIPasskey.DeletePasskey))))
 	
 	// GET /users -> Admin Only (List all users)
-	mux.Handle("GET /v1/users", authJWT(requireAdmin(http.HandlerFunc(h.APIUser.GetUsers))))
+	mux.Handle("GET /v1/users", authJWT(models.ScopeUsersRead)(requireAdmin(http.HandlerFunc(h.APIUser.GetUsers))))
 	
 	// POST /users -> Admin Only (Create user manually)
-	mux.Handle("POST /v1/users", authJWT(requireAdmin(http.HandlerFunc(h.APIUser.CreateUser))))
+	mux.Handle("POST /v1/users", authJWT(models.ScopeUsersWrite)(requireAdmin(http.HandlerFunc(h.APIUser.CreateUser))))
 	
 	// GET /users/{id} -> Admin OR Self
-	mux.Handle("GET /v1/users/{id}", authJWT(requireAdminOrSelf(http.HandlerFunc(h.APIUser.GetUser))))
+	mux.Handle("GET /v1/users/{id}", authJWT(models.ScopeUsersRead)(requireAdminOrSelf(http.HandlerFunc(h.APIUser.GetUser))))
 	
 	// PATCH /users/{id} -> Admin Only
-	mux.Handle("PATCH /v1/users/{id}", authJWT(requireAdmin(http.HandlerFunc(h.APIUser.UpdateUser))))
+	mux.Handle("PATCH /v1/users/{id}", authJWT(models.ScopeUsersWrite)(requireAdmin(http.HandlerFunc(h.APIUser.UpdateUser))))
 	
 	// DELETE /users/{id} -> Admin Only
-	mux.Handle("DELETE /v1/users/{id}", authJWT(requireAdmin(http.HandlerFunc(h.APIUser.DeleteUser))))
+	mux.Handle("DELETE /v1/users/{id}", authJWT(models.ScopeUsersWrite)(requireAdmin(http.HandlerFunc(h.APIUser.DeleteUser))))
+
+	// /invitations -> Admin Only
+	mux.Handle("GET /v1/invitations", authJWT(models.ScopeUsersRead)(requireAdmin(http.HandlerFunc(h.APIInvite.GetInvitations))))
+	mux.Handle("POST /v1/invitations", authJWT(models.ScopeUsersWrite)(requireAdmin(http.HandlerFunc(h.APIInvite.CreateInvitation))))
+	mux.Handle("POST /v1/invitations/{id}/resend", authJWT(models.ScopeUsersWrite)(requireAdmin(http.HandlerFunc(h.APIInvite.ResendInvitation))))
+	mux.Handle("DELETE /v1/invitations/{id}", authJWT(models.ScopeUsersWrite)(requireAdmin(http.HandlerFunc(h.APIInvite.DeleteInvitation))))
+
+	// /admin/emails -> Admin Only (Outbound email queue)
+	mux.Handle("GET /v1/admin/emails", authJWT(models.ScopeUsersRead)(requireAdmin(http.HandlerFunc(h.APIOutbox.GetEmails))))
+	mux.Handle("POST /v1/admin/emails/{id}/retry", authJWT(models.ScopeUsersWrite)(requireAdmin(http.HandlerFunc(h.APIOutbox.RetryEmail))))
+
+	// /admin/stats -> Admin Only (Dashboard statistics, cached for a minute)
+	mux.Handle("GET /v1/admin/stats", authJWT(models.ScopeUsersRead)(requireAdmin(http.HandlerFunc(h.APIStats.GetStats))))
+
+	// /users/{id}/api-keys -> Admin OR Self, creating only Self: an admin holding another
+	// user's secret could act as them (API keys need the apikeys:manage scope)
+	mux.Handle("GET /v1/users/{id}/api-keys", authJWT(models.ScopeAPIKeysManage)(requireAdminOrSelf(http.HandlerFunc(h.APIAPIKey.GetAPIKeys))))
+	mux.Handle("POST /v1/users/{id}/api-keys", authJWT(models.ScopeAPIKeysManage)(requireSelf(http.HandlerFunc(h.APIAPIKey.CreateAPIKey))))
+	mux.Handle("DELETE /v1/users/{id}/api-keys/{keyId}", authJWT(models.ScopeAPIKeysManage)(requireAdminOrSelf(http.HandlerFunc(h.APIAPIKey.DeleteAPIKey))))
 
 	// ---------------------------
 	// Global Middleware Chain
 	// ---------------------------
-	handler := security(mux)
+	handler := security(middleware.Unmatched(mux, writeError))
 	handler = csrf(handler)
+	handler = recoverer(handler)
 	handler = logger(handler)
+
+	// Outside the logger, which records the handler's status and not the encoding
+	handler = compress(handler)
 	
 	if cfg.App.Env == "production" {
 		handler = rateLimit(handler)
 	}
 
+	// Before anything can answer, so even a rate limited client gets its language
+	handler = locale(handler)
+
+	// Outermost so every log line and response, even a rate limited one, carries the ID
+	handler = requestID(handler)
+
 	return handler
 }
\ No newline at end of file
@@ -0,0 +1,176 @@
+package routes
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	apiHandlers "starter-kit-fullstack-gonethttp-template/internal/handlers/api"
+	webHandlers "starter-kit-fullstack-gonethttp-template/internal/handlers/web"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/services"
+	"starter-kit-fullstack-gonethttp-template/pkg/assets"
+	"starter-kit-fullstack-gonethttp-template/pkg/flash"
+	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+	"starter-kit-fullstack-gonethttp-template/pkg/view"
+	"starter-kit-fullstack-gonethttp-template/web"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+)
+
+// The stubs embed their interface, only the methods the tests reach are implemented
+
+type stubUsers struct {
+	services.UserService
+}
+
+func (stubUsers) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return &models.User{ID: id, Name: "Jane", Email: "jane@example.com", Role: models.RoleUser}, nil
+}
+
+// stubAPIKeys accepts any key as one of userID's, holding every scope
+type stubAPIKeys struct {
+	services.APIKeyService
+	userID uuid.UUID
+}
+
+func (s stubAPIKeys) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	return &models.APIKey{UserID: s.userID, Scopes: models.APIKeyScopes}, nil
+}
+
+// stubPasskeys records which credential changes reached the service
+type stubPasskeys struct {
+	services.WebAuthnService
+	calls []string
+}
+
+func (s *stubPasskeys) BeginRegistration(ctx context.Context, userID uuid.UUID) (*protocol.CredentialCreation, uuid.UUID, error) {
+	s.calls = append(s.calls, "BeginRegistration")
+	return &protocol.CredentialCreation{}, uuid.New(), nil
+}
+
+func (s *stubPasskeys) FinishRegistration(ctx context.Context, userID, sessionID uuid.UUID, name string, body io.Reader) (*models.WebAuthnCredential, error) {
+	s.calls = append(s.calls, "FinishRegistration")
+	return &models.WebAuthnCredential{UserID: userID}, nil
+}
+
+func (s *stubPasskeys) SetPasskeyRequired(ctx context.Context, userID uuid.UUID, required bool) (*models.User, error) {
+	s.calls = append(s.calls, "SetPasskeyRequired")
+	return &models.User{ID: userID}, nil
+}
+
+func (s *stubPasskeys) DeleteCredential(ctx context.Context, userID, id uuid.UUID) error {
+	s.calls = append(s.calls, "DeleteCredential")
+	return nil
+}
+
+func newTestRouter(t *testing.T, userID uuid.UUID, passkeys services.WebAuthnService) (*config.Config, http.Handler) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.App.Name = "Starter Kit"
+	cfg.App.Env = "test"
+	cfg.App.URL = "http://localhost:8080"
+	cfg.JWT.Secret = "test-secret"
+
+	webFS := web.FS(cfg.App.Env)
+	sub := func(dir string) fs.FS {
+		fsys, err := fs.Sub(webFS, dir)
+		if err != nil {
+			t.Fatalf("sub %s: %v", dir, err)
+		}
+		return fsys
+	}
+	static, err := assets.New(sub("static"), "/assets/", false)
+	if err != nil {
+		t.Fatalf("assets: %v", err)
+	}
+	if err := view.Init(cfg, sub("templates"), static); err != nil {
+		t.Fatalf("views: %v", err)
+	}
+	flash.Init(cfg.JWT.Secret)
+
+	h := Handlers{
+		APIPasskey: apiHandlers.NewWebAuthnHandler(passkeys, cfg),
+		WebAuth:    webHandlers.NewAuthHandler(nil, cfg),
+	}
+	return cfg, RegisterRoutes(cfg, h, stubUsers{}, stubAPIKeys{userID: userID}, nil, static)
+}
+
+var csrfMeta = regexp.MustCompile(`name="csrf-token" content="([^"]+)"`)
+
+// csrfSession fetches the login page for the CSRF cookie and the token that goes with it
+func csrfSession(t *testing.T, handler http.Handler) (*http.Cookie, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+	match := csrfMeta.FindStringSubmatch(rec.Body.String())
+	if rec.Code != http.StatusOK || match == nil {
+		t.Fatalf("login page: status %d without a CSRF token", rec.Code)
+	}
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "csrf_session" {
+			return cookie, match[1]
+		}
+	}
+	t.Fatal("login page set no CSRF cookie")
+	return nil, ""
+}
+
+func TestPasskeyChangesRejectAPIKeys(t *testing.T) {
+	routes := []struct {
+		method, path, body, call string
+	}{
+		{http.MethodPost, "/v1/auth/webauthn/register/begin", "", "BeginRegistration"},
+		{http.MethodPost, "/v1/auth/webauthn/register/finish?session=" + uuid.NewString(), "{}", "FinishRegistration"},
+		{http.MethodPut, "/v1/me/passkeys/required", `{"required":false}`, "SetPasskeyRequired"},
+		{http.MethodDelete, "/v1/me/passkeys/" + uuid.NewString(), "", "DeleteCredential"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			userID := uuid.New()
+			passkeys := &stubPasskeys{}
+			cfg, handler := newTestRouter(t, userID, passkeys)
+			cookie, token := csrfSession(t, handler)
+
+			accessToken, _, err := utils.GenerateToken(userID, time.Hour, "access", cfg.JWT.Secret)
+			if err != nil {
+				t.Fatalf("access token: %v", err)
+			}
+
+			send := func(authorization string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", authorization)
+				req.Header.Set("X-CSRF-Token", token)
+				req.AddCookie(cookie)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			rec := send("ApiKey sk_leaked")
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("with an API key: status %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body)
+			}
+			if len(passkeys.calls) != 0 {
+				t.Fatalf("with an API key the service was called: %v", passkeys.calls)
+			}
+
+			// The user's own token still gets through
+			rec = send("Bearer " + accessToken)
+			if len(passkeys.calls) != 1 || passkeys.calls[0] != route.call {
+				t.Errorf("with the user's token: status %d, service calls %v, want %s", rec.Code, passkeys.calls, route.call)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type accountService struct {
+	userRepo     repository.UserRepository
+	tokenRepo    repository.TokenRepository
+	apiKeyRepo   repository.APIKeyRepository
+	deviceRepo   repository.DeviceRepository
+	webAuthnRepo repository.WebAuthnRepository
+	inviteRepo   repository.InvitationRepository
+	auditRepo    repository.AuditRepository
+	emailService EmailService
+	cfg          *config.Config
+}
+
+func NewAccountService(uRepo repository.UserRepository, tRepo repository.TokenRepository, kRepo repository.APIKeyRepository, dRepo repository.DeviceRepository, wRepo repository.WebAuthnRepository, iRepo repository.InvitationRepository, aRepo repository.AuditRepository, eService EmailService, cfg *config.Config) AccountService {
+	return &accountService{
+		userRepo:     uRepo,
+		tokenRepo:    tRepo,
+		apiKeyRepo:   kRepo,
+		deviceRepo:   dRepo,
+		webAuthnRepo: wRepo,
+		inviteRepo:   iRepo,
+		auditRepo:    aRepo,
+		emailService: eService,
+		cfg:          cfg,
+	}
+}
+
+func (s *accountService) ExportData(ctx context.Context, userID uuid.UUID) (*AccountExport, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.ExportData")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	refreshTokens, err := s.tokenRepo.FindAllByUserIDAndType(ctx, user.ID.String(), models.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]SessionExport, 0, len(refreshTokens))
+	for _, t := range refreshTokens {
+		sessions = append(sessions, SessionExport{ID: t.ID, CreatedAt: t.CreatedAt, Expires: t.Expires})
+	}
+
+	apiKeys, err := s.apiKeyRepo.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := s.deviceRepo.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.webAuthnRepo.FindCredentialsByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog, err := s.auditRepo.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountExport{
+		ExportedAt: time.Now(),
+		Profile:    user,
+		Sessions:   sessions,
+		APIKeys:    apiKeys,
+		Devices:    devices,
+		Passkeys:   passkeys,
+		AuditLog:   auditLog,
+	}, nil
+}
+
+func (s *accountService) ScheduleDeletion(ctx context.Context, userID uuid.UUID, req DeleteAccountRequest) (*time.Time, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.ScheduleDeletion")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if !user.ComparePassword(req.Password) {
+		return nil, ErrIncorrectPassword
+	}
+
+	purgeAt := time.Now().AddDate(0, 0, s.cfg.Account.DeletionGraceDays)
+	user.DeletionScheduledAt = &purgeAt
+	// A scheduled admin no longer counts as one, so another has to be left
+	if user.Role == models.RoleAdmin {
+		if err := updateUnlessLastAdmin(ctx, s.userRepo, user); err != nil {
+			return nil, err
+		}
+	} else if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if s.cfg.Account.DeletionGraceDays <= 0 {
+		if err := s.purge(ctx, user); err != nil {
+			return nil, err
+		}
+		return nil, s.emailService.SendAccountDeletionEmail(ctx, user.Email, user.Locale, time.Time{})
+	}
+
+	// Sign the user out everywhere, they can still sign in again to cancel
+	if err := s.revokeCredentials(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return &purgeAt, s.emailService.SendAccountDeletionEmail(ctx, user.Email, user.Locale, purgeAt)
+}
+
+func (s *accountService) PurgeScheduledDeletions(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.PurgeScheduledDeletions")
+	defer span.End()
+
+	users, err := s.userRepo.FindScheduledForDeletion(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range users {
+		if err := s.purge(ctx, &users[i]); err != nil {
+			return purged, err
+		}
+		purged++
+		// Notification failures must not stop the purge
+		s.emailService.SendAccountDeletionEmail(ctx, users[i].Email, users[i].Locale, time.Time{})
+	}
+	return purged, nil
+}
+
+func (s *accountService) Purge(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "AccountService.Purge")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	return s.purge(ctx, user)
+}
+
+// purge permanently removes the user, everything that can authenticate as them, their
+// audit log and the invitations they sent that could still let someone in
+func (s *accountService) purge(ctx context.Context, user *models.User) error {
+	if err := s.revokeCredentials(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.deviceRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.webAuthnRepo.DeleteCredentialsByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.inviteRepo.DeleteOpenByInviter(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.auditRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.userRepo.Delete(ctx, user.ID)
+}
+
+func (s *accountService) revokeCredentials(ctx context.Context, userID uuid.UUID) error {
+	if err := s.tokenRepo.DeleteByUserID(ctx, userID.String()); err != nil {
+		return err
+	}
+	return s.apiKeyRepo.DeleteByUserID(ctx, userID)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+)
+
+// stubEmails accepts the account emails without sending them
+type stubEmails struct {
+	EmailService
+}
+
+func (stubEmails) SendAccountDeletionEmail(ctx context.Context, to, locale string, purgeAt time.Time) error {
+	return nil
+}
+
+// newTestAccounts builds the account service on a fresh database, with the audit log
+// subscribed to events and a week of grace before deletions
+func newTestAccounts(t *testing.T) (AccountService, repository.UserRepository, *EventBus) {
+	t.Helper()
+	db := newTestDB(t, &models.User{}, &models.Token{}, &models.APIKey{}, &models.Invitation{}, &models.Device{}, &models.WebAuthnCredential{}, &models.AuditEntry{})
+
+	users := repository.NewUserRepository(db)
+	audit := repository.NewAuditRepository(db)
+	events := NewEventBus()
+	SubscribeAuditLog(events, audit)
+
+	cfg := &config.Config{}
+	cfg.Account.DeletionGraceDays = 7
+
+	accounts := NewAccountService(users, repository.NewTokenRepository(db), repository.NewAPIKeyRepository(db), repository.NewDeviceRepository(db),
+		repository.NewWebAuthnRepository(db), repository.NewInvitationRepository(db), audit, stubEmails{}, cfg)
+	return accounts, users, events
+}
+
+func TestExportIncludesAuditLog(t *testing.T) {
+	ctx := context.Background()
+	accounts, users, events := newTestAccounts(t)
+
+	user := &models.User{Name: "Jane", Email: "jane@example.com", Password: "hash", Role: models.RoleUser}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	other := &models.User{Name: "John", Email: "john@example.com", Password: "hash", Role: models.RoleUser}
+	if err := users.Create(ctx, other); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	events.Publish(ctx, Event{Type: EventLogin, User: user, Client: ClientInfo{IP: "203.0.113.7", UserAgent: "test"}})
+	events.Publish(ctx, Event{Type: EventEmailChanged, User: user, OldEmail: "old@example.com", NewEmail: "jane@example.com"})
+	events.Publish(ctx, Event{Type: EventLogin, User: other})
+
+	export, err := accounts.ExportData(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(export.AuditLog) != 2 {
+		t.Fatalf("audit log has %d entries, want the user's 2", len(export.AuditLog))
+	}
+	changed, login := export.AuditLog[0], export.AuditLog[1]
+	if changed.Action != EventEmailChanged || !slices.Equal(changed.Details, []string{"from old@example.com", "to jane@example.com"}) {
+		t.Errorf("latest entry = %s %v, want the email change", changed.Action, changed.Details)
+	}
+	if login.Action != EventLogin || login.IP != "203.0.113.7" {
+		t.Errorf("oldest entry = %s from %q, want the login from 203.0.113.7", login.Action, login.IP)
+	}
+}
+
+func TestScheduledAdminIsNotCountedAsAdmin(t *testing.T) {
+	ctx := context.Background()
+	accounts, users, _ := newTestAccounts(t)
+
+	first := &models.User{Name: "Jane", Email: "jane@example.com", Password: "password1", Role: models.RoleAdmin}
+	second := &models.User{Name: "John", Email: "john@example.com", Password: "password1", Role: models.RoleAdmin}
+	for _, admin := range []*models.User{first, second} {
+		if err := users.Create(ctx, admin); err != nil {
+			t.Fatalf("create admin: %v", err)
+		}
+	}
+
+	if _, err := accounts.ScheduleDeletion(ctx, first.ID, DeleteAccountRequest{Password: "password1"}); err != nil {
+		t.Fatalf("first admin's deletion: %v", err)
+	}
+	if admins, err := users.CountByRole(ctx, models.RoleAdmin); err != nil || admins != 1 {
+		t.Fatalf("admins = %d (%v), want 1 once the first is scheduled for deletion", admins, err)
+	}
+
+	// The first admin is on their way out, so the second is the last one
+	if _, err := accounts.ScheduleDeletion(ctx, second.ID, DeleteAccountRequest{Password: "password1"}); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("second admin's deletion: %v, want %v", err, ErrLastAdmin)
+	}
+	if user, _ := users.FindByID(ctx, second.ID); user.DeletionScheduledAt != nil {
+		t.Error("the last admin was scheduled for deletion")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+)
+
+// auditEvents are the account events kept in the audit log
+var auditEvents = []string{
+	EventLogin,
+	EventPasswordReset,
+	EventPasswordChanged,
+	EventEmailChangeRequested,
+	EventEmailChanged,
+	EventAdminUpdatedAccount,
+}
+
+type auditLog struct {
+	repo repository.AuditRepository
+}
+
+// SubscribeAuditLog records the account events published on bus as audit entries of the
+// user they happened to
+func SubscribeAuditLog(bus *EventBus, repo repository.AuditRepository) {
+	l := &auditLog{repo: repo}
+	for _, event := range auditEvents {
+		bus.Subscribe(event, l.record)
+	}
+}
+
+func (l *auditLog) record(ctx context.Context, e Event) {
+	entry := &models.AuditEntry{
+		UserID:    e.User.ID,
+		Action:    e.Type,
+		IP:        e.Client.IP,
+		UserAgent: e.Client.UserAgent,
+		Details:   e.Changes,
+		CreatedAt: e.OccurredAt,
+	}
+	if e.OldEmail != "" || e.NewEmail != "" {
+		entry.Details = append(entry.Details, "from "+e.OldEmail, "to "+e.NewEmail)
+	}
+
+	// The change itself already happened, a lost entry must not fail it
+	if err := l.repo.Create(ctx, entry); err != nil {
+		slog.ErrorContext(ctx, "Audit log failed", "event", e.Type, "account_id", e.User.ID, "error", err)
+	}
+}
\ No newline at end of file
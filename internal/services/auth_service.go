@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"starter-kit-fullstack-gonethttp-template/internal/repository"
 	"starter-kit-fullstack-gonethttp-template/pkg/utils"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -17,50 +19,101 @@ type authService struct {
 	tokenRepo    repository.TokenRepository
 	tokenService *TokenService
 	emailService EmailService
+	events       *EventBus
+	magicLimiter *emailLimiter
 	cfg          *config.Config
 }
 
-func NewAuthService(uRepo repository.UserRepository, tRepo repository.TokenRepository, tService *TokenService, eService EmailService, cfg *config.Config) AuthService {
+var (
+	ErrInvalidCredentials   = newError(KindUnauthorized, "invalid_credentials", "incorrect email or password")
+	ErrEmailUnchanged       = newError(KindValidation, "email_unchanged", "new email must be different from the current one")
+	ErrMagicLinkRateLimited = newError(KindRateLimited, "magic_link_rate_limited", "too many sign-in links requested, try again later")
+	ErrInvalidMagicLink     = newError(KindUnauthorized, "invalid_magic_link", "this sign-in link is invalid, has expired or was already used")
+)
+
+func NewAuthService(uRepo repository.UserRepository, tRepo repository.TokenRepository, tService *TokenService, eService EmailService, events *EventBus, cfg *config.Config) AuthService {
 	return &authService{
 		userRepo:     uRepo,
 		tokenRepo:    tRepo,
 		tokenService: tService,
 		emailService: eService,
+		events:       events,
+		magicLimiter: newEmailLimiter(cfg.Auth.MagicLinkPerHour),
 		cfg:          cfg,
 	}
 }
 
-func (s *authService) Login(email, password string) (*models.User, map[string]interface{}, error) {
-	user, err := s.userRepo.FindByEmail(email)
+func (s *authService) Login(ctx context.Context, email, password string, client ClientInfo) (*models.User, map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil || !user.ComparePassword(password) {
-		return nil, nil, errors.New("incorrect email or password")
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if user.PasskeyRequired {
+		return nil, nil, s.requirePasskey(ctx, user)
 	}
 
-	tokens, err := s.tokenService.GenerateAuthTokens(user.ID)
+	tokens, err := startSession(ctx, s.userRepo, s.tokenService, s.events, user, client)
 	if err != nil {
 		return nil, nil, err
 	}
-
 	return user, tokens, nil
 }
 
-func (s *authService) Register(req RegisterRequest) (*models.User, map[string]interface{}, error) {
-	if exists, _ := s.userRepo.ExistsByEmail(req.Email); exists {
-		return nil, nil, errors.New("email already taken")
+// requirePasskey issues the short-lived token that lets the user continue with their passkey
+func (s *authService) requirePasskey(ctx context.Context, user *models.User) error {
+	tokenStr, expTime, err := utils.GenerateToken(user.ID, webAuthnSessionTTL, models.TokenTypePasskeyLogin, s.cfg.JWT.Secret)
+	if err != nil {
+		return err
+	}
+	if err := s.tokenService.SaveToken(ctx, tokenStr, user.ID.String(), expTime, models.TokenTypePasskeyLogin); err != nil {
+		return err
+	}
+	return &PasskeyRequiredError{Token: tokenStr}
+}
+
+// startSession finishes any sign-in: it cancels a pending self-deletion (signing in during
+// the grace period does that), issues the token pair and announces the login
+func startSession(ctx context.Context, userRepo repository.UserRepository, tokenService *TokenService, events *EventBus, user *models.User, client ClientInfo) (map[string]interface{}, error) {
+	if user.DeletionScheduledAt != nil {
+		user.DeletionScheduledAt = nil
+		if err := userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := tokenService.GenerateAuthTokens(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	events.Publish(ctx, Event{Type: EventLogin, User: user, Client: client})
+	return tokens, nil
+}
+
+func (s *authService) Register(ctx context.Context, req RegisterRequest) (*models.User, map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	if exists, _ := s.userRepo.ExistsByEmail(ctx, req.Email); exists {
+		return nil, nil, ErrEmailTaken
 	}
 
 	user := &models.User{
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: req.Password,
-		Role:     "user", // Default role
+		Role:     models.RoleUser, // Default role
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, nil, err
 	}
 
-	tokens, err := s.tokenService.GenerateAuthTokens(user.ID)
+	tokens, err := s.tokenService.GenerateAuthTokens(ctx, user.ID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -68,33 +121,42 @@ func (s *authService) Register(req RegisterRequest) (*models.User, map[string]in
 	return user, tokens, nil
 }
 
-func (s *authService) Logout(refreshToken string) error {
-	tokenDoc, err := s.tokenService.VerifyToken(refreshToken, models.TokenTypeRefresh)
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	tokenDoc, err := s.tokenService.VerifyToken(ctx, refreshToken, models.TokenTypeRefresh)
 	if err != nil {
-		return errors.New("not found")
+		return ErrInvalidToken
 	}
-	return s.tokenRepo.Delete(tokenDoc)
+	return s.tokenRepo.Delete(ctx, tokenDoc)
 }
 
-func (s *authService) RefreshAuth(refreshToken string) (map[string]interface{}, error) {
-	tokenDoc, err := s.tokenService.VerifyToken(refreshToken, models.TokenTypeRefresh)
+func (s *authService) RefreshAuth(ctx context.Context, refreshToken string) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.RefreshAuth")
+	defer span.End()
+
+	tokenDoc, err := s.tokenService.VerifyToken(ctx, refreshToken, models.TokenTypeRefresh)
 	if err != nil {
-		return nil, errors.New("please authenticate")
+		return nil, ErrInvalidToken
 	}
 
 	userUUID, err := uuid.Parse(tokenDoc.UserID)
 	if err != nil {
-		return nil, errors.New("invalid user data")
+		return nil, ErrInvalidToken
 	}
 
 	// Clean up old refresh token
-	s.tokenRepo.Delete(tokenDoc)
+	s.tokenRepo.Delete(ctx, tokenDoc)
 
-	return s.tokenService.GenerateAuthTokens(userUUID)
+	return s.tokenService.GenerateAuthTokens(ctx, userUUID)
 }
 
-func (s *authService) ForgotPassword(email string) error {
-	user, err := s.userRepo.FindByEmail(email)
+func (s *authService) ForgotPassword(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.ForgotPassword")
+	defer span.End()
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		// Return nil to avoid email enumeration
 		return nil
@@ -106,59 +168,269 @@ func (s *authService) ForgotPassword(email string) error {
 		return err
 	}
 
-	if err := s.tokenService.SaveToken(tokenStr, user.ID.String(), expTime, models.TokenTypeResetPassword); err != nil {
+	if err := s.tokenService.SaveToken(ctx, tokenStr, user.ID.String(), expTime, models.TokenTypeResetPassword); err != nil {
 		return err
 	}
 
-	return s.emailService.SendResetPasswordEmail(user.Email, tokenStr)
+	return s.emailService.SendResetPasswordEmail(ctx, user.Email, user.Locale, tokenStr)
 }
 
-func (s *authService) ResetPassword(tokenStr, newPassword string) error {
-	tokenDoc, err := s.tokenService.VerifyToken(tokenStr, models.TokenTypeResetPassword)
+func (s *authService) RequestMagicLink(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.RequestMagicLink")
+	defer span.End()
+
+	// Throttled before the lookup so unknown addresses behave exactly like real ones
+	if !s.magicLimiter.Allow(email) {
+		return ErrMagicLinkRateLimited
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
-		return errors.New("password reset failed")
+		// Return nil to avoid email enumeration
+		return nil
+	}
+
+	// Only the latest link stays valid
+	if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypeMagicLink); err != nil {
+		return err
+	}
+
+	expires := time.Duration(s.cfg.JWT.MagicLinkExpiration) * time.Minute
+	tokenStr, expTime, err := utils.GenerateToken(user.ID, expires, models.TokenTypeMagicLink, s.cfg.JWT.Secret)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tokenService.SaveToken(ctx, tokenStr, user.ID.String(), expTime, models.TokenTypeMagicLink); err != nil {
+		return err
+	}
+
+	return s.emailService.SendMagicLinkEmail(ctx, user.Email, user.Locale, tokenStr)
+}
+
+func (s *authService) LoginWithMagicLink(ctx context.Context, tokenStr string, client ClientInfo) (*models.User, map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.LoginWithMagicLink")
+	defer span.End()
+
+	tokenDoc, err := s.tokenService.VerifyToken(ctx, tokenStr, models.TokenTypeMagicLink)
+	if err != nil {
+		return nil, nil, ErrInvalidMagicLink
+	}
+
+	// Two concurrent requests may both find the token, only the one that deletes it signs in
+	consumed, err := s.tokenRepo.Consume(ctx, tokenDoc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !consumed {
+		return nil, nil, ErrInvalidMagicLink
 	}
 
 	userUUID, err := uuid.Parse(tokenDoc.UserID)
 	if err != nil {
-		return errors.New("invalid user data")
+		return nil, nil, ErrInvalidMagicLink
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userUUID)
+	if err != nil {
+		return nil, nil, ErrInvalidMagicLink
+	}
+
+	// Following the link proves the address
+	if !user.IsEmailVerified {
+		user.IsEmailVerified = true
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if user.PasskeyRequired {
+		return nil, nil, s.requirePasskey(ctx, user)
+	}
+
+	tokens, err := startSession(ctx, s.userRepo, s.tokenService, s.events, user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+func (s *authService) ResetPassword(ctx context.Context, tokenStr, newPassword string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.ResetPassword")
+	defer span.End()
+
+	userUUID, err := s.linkToken(ctx, tokenStr, models.TokenTypeResetPassword)
+	if err != nil {
+		return err
 	}
 
-	user, err := s.userRepo.FindByID(userUUID)
+	user, err := s.userRepo.FindByID(ctx, userUUID)
 	if err != nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	user.Password = newPassword
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		return err
 	}
 
 	// Invalidate all reset tokens for this user
-	return s.tokenRepo.DeleteByUserIDAndType(user.ID.String(), models.TokenTypeResetPassword)
+	if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypeResetPassword); err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, Event{Type: EventPasswordReset, User: user})
+	return nil
 }
 
-func (s *authService) VerifyEmail(tokenStr string) error {
-	tokenDoc, err := s.tokenService.VerifyToken(tokenStr, models.TokenTypeVerifyEmail)
+func (s *authService) VerifyEmail(ctx context.Context, tokenStr string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.VerifyEmail")
+	defer span.End()
+
+	userUUID, err := s.linkToken(ctx, tokenStr, models.TokenTypeVerifyEmail)
 	if err != nil {
-		return errors.New("email verification failed")
+		return err
 	}
 
-	userUUID, err := uuid.Parse(tokenDoc.UserID)
+	user, err := s.userRepo.FindByID(ctx, userUUID)
 	if err != nil {
-		return errors.New("invalid user data")
+		return ErrUserNotFound
 	}
 
-	user, err := s.userRepo.FindByID(userUUID)
-	if err != nil {
-		return errors.New("user not found")
+	// A pending address (requested via RequestEmailChange) is only applied once verified
+	oldEmail := user.Email
+	if user.PendingEmail != "" {
+		if exists, _ := s.userRepo.ExistsByEmail(ctx, user.PendingEmail); exists {
+			return ErrEmailTaken
+		}
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
 	}
 
 	user.IsEmailVerified = true
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		return err
 	}
 
 	// Invalidate verify tokens
-	return s.tokenRepo.DeleteByUserIDAndType(user.ID.String(), models.TokenTypeVerifyEmail)
+	if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypeVerifyEmail); err != nil {
+		return err
+	}
+
+	if user.Email != oldEmail {
+		s.events.Publish(ctx, Event{Type: EventEmailChanged, User: user, OldEmail: oldEmail, NewEmail: user.Email})
+	}
+	return nil
+}
+
+func (s *authService) CheckResetPasswordToken(ctx context.Context, tokenStr string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.CheckResetPasswordToken")
+	defer span.End()
+
+	_, err := s.linkToken(ctx, tokenStr, models.TokenTypeResetPassword)
+	return err
+}
+
+func (s *authService) CheckVerifyEmailToken(ctx context.Context, tokenStr string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.CheckVerifyEmailToken")
+	defer span.End()
+
+	_, err := s.linkToken(ctx, tokenStr, models.TokenTypeVerifyEmail)
+	return err
+}
+
+// linkToken verifies a token sent by email and returns the user it was issued to. An
+// expired one is told apart, so pages can offer a new link rather than a dead end.
+func (s *authService) linkToken(ctx context.Context, tokenStr, tokenType string) (uuid.UUID, error) {
+	tokenDoc, err := s.tokenService.VerifyToken(ctx, tokenStr, tokenType)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return uuid.Nil, ErrTokenExpired
+	}
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	userUUID, err := uuid.Parse(tokenDoc.UserID)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return userUUID, nil
+}
+
+func (s *authService) ChangePassword(ctx context.Context, userID uuid.UUID, req ChangePasswordRequest) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if !user.ComparePassword(req.CurrentPassword) {
+		return nil, ErrIncorrectPassword
+	}
+
+	user.Password = req.NewPassword
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Revoke every other session and pending reset link, then issue a fresh pair for the caller
+	if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypeRefresh); err != nil {
+		return nil, err
+	}
+	if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypeResetPassword); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, Event{Type: EventPasswordChanged, User: user})
+	return s.tokenService.GenerateAuthTokens(ctx, user.ID)
+}
+
+func (s *authService) RequestEmailChange(ctx context.Context, userID uuid.UUID, req ChangeEmailRequest) error {
+	ctx, span := tracer.Start(ctx, "AuthService.RequestEmailChange")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if !user.ComparePassword(req.Password) {
+		return ErrIncorrectPassword
+	}
+
+	if req.Email == user.Email {
+		return ErrEmailUnchanged
+	}
+	if exists, _ := s.userRepo.ExistsByEmail(ctx, req.Email); exists {
+		return ErrEmailTaken
+	}
+
+	user.PendingEmail = req.Email
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	// Only the latest verification link stays valid
+	if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypeVerifyEmail); err != nil {
+		return err
+	}
+
+	expires := time.Duration(s.cfg.JWT.VerifyEmailExpiration) * time.Minute
+	tokenStr, expTime, err := utils.GenerateToken(user.ID, expires, models.TokenTypeVerifyEmail, s.cfg.JWT.Secret)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tokenService.SaveToken(ctx, tokenStr, user.ID.String(), expTime, models.TokenTypeVerifyEmail); err != nil {
+		return err
+	}
+
+	if err := s.emailService.SendVerificationEmail(ctx, req.Email, user.Locale, tokenStr); err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, Event{Type: EventEmailChangeRequested, User: user, OldEmail: user.Email, NewEmail: req.Email})
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// emailLimiter throttles requests per email address (in memory, per instance).
+// Addresses are limited whether or not an account exists, so a 429 reveals nothing.
+type emailLimiter struct {
+	mu        sync.Mutex
+	limiters  map[string]*emailLimiterEntry
+	lastPrune time.Time
+	r         rate.Limit
+	b         int
+}
+
+type emailLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newEmailLimiter(perHour int) *emailLimiter {
+	if perHour < 1 {
+		perHour = 1
+	}
+	return &emailLimiter{
+		limiters: make(map[string]*emailLimiterEntry),
+		r:        rate.Every(time.Hour / time.Duration(perHour)),
+		b:        perHour,
+	}
+}
+
+func (l *emailLimiter) Allow(email string) bool {
+	key := strings.ToLower(strings.TrimSpace(email))
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, exists := l.limiters[key]
+	if !exists {
+		l.prune(now)
+		entry = &emailLimiterEntry{limiter: rate.NewLimiter(l.r, l.b)}
+		l.limiters[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter.AllowN(now, 1)
+}
+
+// prune drops addresses idle for an hour (their bucket has refilled by then), at most once a minute
+func (l *emailLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	for key, entry := range l.limiters {
+		if now.Sub(entry.lastSeen) > time.Hour {
+			delete(l.limiters, key)
+		}
+	}
+}
\ No newline at end of file
@@ -1,53 +1,112 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"log"
-	"net/smtp"
+	"time"
 
 	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/mailer"
 )
 
 type emailService struct {
-	cfg *config.Config
+	cfg      *config.Config
+	renderer *mailer.Renderer
+	outbox   OutboxService
 }
 
-func NewEmailService(cfg *config.Config) EmailService {
-	return &emailService{cfg: cfg}
+func NewEmailService(cfg *config.Config, renderer *mailer.Renderer, outbox OutboxService) EmailService {
+	return &emailService{cfg: cfg, renderer: renderer, outbox: outbox}
 }
 
-func (s *emailService) SendEmail(to, subject, body string) error {
-	// In development, we just log the email to console (Mock)
-	if s.cfg.App.Env == "development" && s.cfg.SMTP.Host == "" {
-		log.Println("--- MOCK EMAIL ---")
-		log.Printf("To: %s\n", to)
-		log.Printf("Subject: %s\n", subject)
-		log.Printf("Body: %s\n", body)
-		log.Println("------------------")
-		return nil
+// SendTemplate renders web/templates/emails/<locale>/<name>.{html,txt} and queues it for delivery
+func (s *emailService) SendTemplate(ctx context.Context, idempotencyKey, to, locale, name string, data map[string]interface{}) error {
+	ctx, span := tracer.Start(ctx, "EmailService.SendTemplate")
+	defer span.End()
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["AppName"] = s.cfg.App.Name
+	data["AppURL"] = s.cfg.App.URL
+	if locale == "" {
+		locale = i18n.Locale(ctx)
 	}
 
-	// SMTP Implementation
-	addr := fmt.Sprintf("%s:%d", s.cfg.SMTP.Host, s.cfg.SMTP.Port)
-	auth := smtp.PlainAuth("", s.cfg.SMTP.Username, s.cfg.SMTP.Password, s.cfg.SMTP.Host)
+	content, err := s.renderer.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+
+	return s.outbox.Enqueue(ctx, &models.OutboxEmail{
+		IdempotencyKey: idempotencyKey,
+		Template:       name,
+		To:             to,
+		Subject:        content.Subject,
+		Text:           content.Text,
+		HTML:           content.HTML,
+	})
+}
+
+func (s *emailService) SendResetPasswordEmail(ctx context.Context, to, locale, token string) error {
+	ctx, span := tracer.Start(ctx, "EmailService.SendResetPasswordEmail")
+	defer span.End()
+
+	return s.SendTemplate(ctx, tokenKey("reset-password", token), to, locale, "reset-password", map[string]interface{}{
+		"URL": fmt.Sprintf("%s/reset-password?token=%s", s.cfg.App.URL, token),
+	})
+}
+
+func (s *emailService) SendVerificationEmail(ctx context.Context, to, locale, token string) error {
+	ctx, span := tracer.Start(ctx, "EmailService.SendVerificationEmail")
+	defer span.End()
 
-	msg := []byte(fmt.Sprintf("To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"Content-Type: text/plain; charset=\"utf-8\"\r\n"+
-		"\r\n"+
-		"%s\r\n", to, subject, body))
+	return s.SendTemplate(ctx, tokenKey("verify-email", token), to, locale, "verify-email", map[string]interface{}{
+		"URL": fmt.Sprintf("%s/verify-email?token=%s", s.cfg.App.URL, token),
+	})
+}
+
+func (s *emailService) SendInvitationEmail(ctx context.Context, to, locale, token string) error {
+	ctx, span := tracer.Start(ctx, "EmailService.SendInvitationEmail")
+	defer span.End()
 
-	return smtp.SendMail(addr, auth, s.cfg.SMTP.From, []string{to}, msg)
+	return s.SendTemplate(ctx, tokenKey("invitation", token), to, locale, "invitation", map[string]interface{}{
+		"URL": fmt.Sprintf("%s/accept-invite?token=%s", s.cfg.App.URL, token),
+	})
 }
 
-func (s *emailService) SendResetPasswordEmail(to, token string) error {
-	resetURL := fmt.Sprintf("%s/reset-password?token=%s", s.cfg.App.URL, token)
-	text := fmt.Sprintf("Dear user,\n\nTo reset your password, click on this link: %s\n\nIf you did not request any password resets, then ignore this email.", resetURL)
-	return s.SendEmail(to, "Reset Password", text)
+func (s *emailService) SendMagicLinkEmail(ctx context.Context, to, locale, token string) error {
+	ctx, span := tracer.Start(ctx, "EmailService.SendMagicLinkEmail")
+	defer span.End()
+
+	return s.SendTemplate(ctx, tokenKey("magic-link", token), to, locale, "magic-link", map[string]interface{}{
+		"URL":       fmt.Sprintf("%s/auth/magic?token=%s", s.cfg.App.URL, token),
+		"ExpiresIn": s.cfg.JWT.MagicLinkExpiration,
+	})
 }
 
-func (s *emailService) SendVerificationEmail(to, token string) error {
-	verifyURL := fmt.Sprintf("%s/verify-email?token=%s", s.cfg.App.URL, token)
-	text := fmt.Sprintf("Dear user,\n\nTo verify your email, click on this link: %s\n\nIf you did not create an account, then ignore this email.", verifyURL)
-	return s.SendEmail(to, "Email Verification", text)
-}
\ No newline at end of file
+func (s *emailService) SendAccountDeletionEmail(ctx context.Context, to, locale string, purgeAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "EmailService.SendAccountDeletionEmail")
+	defer span.End()
+
+	if purgeAt.IsZero() {
+		// No natural key: the same address may sign up and delete again later
+		return s.SendTemplate(ctx, "", to, locale, "account-deleted", nil)
+	}
+
+	key := fmt.Sprintf("account-deletion-scheduled:%s:%d", to, purgeAt.Unix())
+	return s.SendTemplate(ctx, key, to, locale, "account-deletion-scheduled", map[string]interface{}{
+		"PurgeAt": purgeAt,
+		"URL":     fmt.Sprintf("%s/login", s.cfg.App.URL),
+	})
+}
+
+// tokenKey derives an idempotency key from a one-time token without exposing the token itself
+func tokenKey(template, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return template + ":" + hex.EncodeToString(sum[:])
+}
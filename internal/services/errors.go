@@ -0,0 +1,38 @@
+package services
+
+// ErrorKind classifies a domain error; the API maps each kind to one HTTP status
+type ErrorKind int
+
+const (
+	KindValidation ErrorKind = iota + 1
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindRateLimited
+)
+
+// Error is a failure the caller can act on. Its message is safe to show to clients,
+// anything that is not an *Error (database, mailer, ...) is treated as internal.
+type Error struct {
+	Kind    ErrorKind
+	Code    string // Machine readable, e.g. "email_taken"
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(kind ErrorKind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Shared by several services
+var (
+	ErrUserNotFound      = newError(KindNotFound, "user_not_found", "user not found")
+	ErrEmailTaken        = newError(KindConflict, "email_taken", "email already taken")
+	ErrIncorrectPassword = newError(KindValidation, "incorrect_password", "password is incorrect")
+	ErrInvalidToken      = newError(KindUnauthorized, "invalid_token", "this link or token is invalid or has expired")
+	ErrTokenExpired      = newError(KindUnauthorized, "token_expired", "this link has expired")
+)
\ No newline at end of file
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+)
+
+// Account events published by the services
+const (
+	EventLogin                = "auth.login"
+	EventPasswordReset        = "account.password_reset"
+	EventPasswordChanged      = "account.password_changed"
+	EventEmailChangeRequested = "account.email_change_requested"
+	EventEmailChanged         = "account.email_changed"
+	EventAdminUpdatedAccount  = "account.admin_updated"
+)
+
+// Event describes something that happened to a user account
+type Event struct {
+	Type       string
+	User       *models.User // State after the change
+	Client     ClientInfo   // Who triggered it, when known
+	OldEmail   string       // Set on email changes so notices reach the previous address
+	NewEmail   string
+	Changes    []string // Fields an admin changed
+	OccurredAt time.Time
+}
+
+type EventHandler func(context.Context, Event)
+
+// EventBus dispatches events to subscribers in-process.
+// Handlers run synchronously, so they must stay cheap (emails only go to the outbox).
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[string][]EventHandler)}
+}
+
+func (b *EventBus) Subscribe(eventType string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish is a no-op on a nil bus so services can be built without one
+func (b *EventBus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers[event.Type]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(ctx, event)
+	}
+}
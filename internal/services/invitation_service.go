@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvitationPending  = newError(KindConflict, "invitation_pending", "an invitation is already pending for this email")
+	ErrInvitationNotFound = newError(KindNotFound, "invitation_not_found", "invitation not found")
+	ErrInvalidInvitation  = newError(KindValidation, "invalid_invitation", "invitation is invalid or has expired")
+)
+
+type invitationService struct {
+	repo         repository.InvitationRepository
+	userRepo     repository.UserRepository
+	tokenService *TokenService
+	emailService EmailService
+	cfg          *config.Config
+}
+
+func NewInvitationService(repo repository.InvitationRepository, uRepo repository.UserRepository, tService *TokenService, eService EmailService, cfg *config.Config) InvitationService {
+	return &invitationService{
+		repo:         repo,
+		userRepo:     uRepo,
+		tokenService: tService,
+		emailService: eService,
+		cfg:          cfg,
+	}
+}
+
+func (s *invitationService) Invite(ctx context.Context, inviterID uuid.UUID, req InviteUserRequest) (*models.Invitation, error) {
+	ctx, span := tracer.Start(ctx, "InvitationService.Invite")
+	defer span.End()
+
+	if exists, _ := s.userRepo.ExistsByEmail(ctx, req.Email); exists {
+		return nil, ErrEmailTaken
+	}
+	if _, err := s.repo.FindOpenByEmail(ctx, req.Email); err == nil {
+		return nil, ErrInvitationPending
+	}
+
+	invitation := &models.Invitation{
+		ID:          uuid.New(),
+		Email:       req.Email,
+		Role:        req.Role,
+		InvitedByID: inviterID,
+	}
+	if err := s.issueToken(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	return invitation, s.emailService.SendInvitationEmail(ctx, invitation.Email, "", invitation.Token)
+}
+
+func (s *invitationService) ListOpen(ctx context.Context) ([]models.Invitation, error) {
+	ctx, span := tracer.Start(ctx, "InvitationService.ListOpen")
+	defer span.End()
+
+	return s.repo.FindOpen(ctx)
+}
+
+func (s *invitationService) Resend(ctx context.Context, id uuid.UUID) (*models.Invitation, error) {
+	ctx, span := tracer.Start(ctx, "InvitationService.Resend")
+	defer span.End()
+
+	invitation, err := s.repo.FindByID(ctx, id)
+	if err != nil || invitation.AcceptedAt != nil {
+		return nil, ErrInvitationNotFound
+	}
+
+	// A fresh token also invalidates the previously emailed link
+	if err := s.issueToken(ctx, invitation); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	return invitation, s.emailService.SendInvitationEmail(ctx, invitation.Email, "", invitation.Token)
+}
+
+func (s *invitationService) Revoke(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "InvitationService.Revoke")
+	defer span.End()
+
+	invitation, err := s.repo.FindByID(ctx, id)
+	if err != nil || invitation.AcceptedAt != nil {
+		return ErrInvitationNotFound
+	}
+	return s.repo.Delete(ctx, invitation)
+}
+
+func (s *invitationService) GetPending(ctx context.Context, token string) (*models.Invitation, error) {
+	ctx, span := tracer.Start(ctx, "InvitationService.GetPending")
+	defer span.End()
+
+	claims, err := utils.ValidateToken(token, s.cfg.JWT.Secret)
+	if err != nil || claims.Type != models.TokenTypeInvite {
+		return nil, ErrInvalidInvitation
+	}
+
+	invitation, err := s.repo.FindByToken(ctx, token)
+	if err != nil || !invitation.IsPending() {
+		return nil, ErrInvalidInvitation
+	}
+	return invitation, nil
+}
+
+func (s *invitationService) Accept(ctx context.Context, token string, req AcceptInviteRequest) (*models.User, map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "InvitationService.Accept")
+	defer span.End()
+
+	invitation, err := s.GetPending(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The address may have registered on its own since the invite was sent
+	if exists, _ := s.userRepo.ExistsByEmail(ctx, invitation.Email); exists {
+		return nil, nil, ErrEmailTaken
+	}
+
+	user := &models.User{
+		Name:            req.Name,
+		Email:           invitation.Email,
+		Password:        req.Password,
+		Role:            invitation.Role,
+		IsEmailVerified: true, // Receiving the invite proves ownership of the address
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	if err := s.repo.Update(ctx, invitation); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.tokenService.GenerateAuthTokens(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// issueToken signs a new invite token for the invitation and resets its expiry
+func (s *invitationService) issueToken(ctx context.Context, invitation *models.Invitation) error {
+	expires := time.Duration(s.cfg.JWT.InviteExpiration) * time.Hour
+	tokenStr, expTime, err := utils.GenerateToken(invitation.ID, expires, models.TokenTypeInvite, s.cfg.JWT.Secret)
+	if err != nil {
+		return err
+	}
+
+	invitation.Token = tokenStr
+	invitation.ExpiresAt = expTime
+	return nil
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"net/mail"
+	"sync"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+	"starter-kit-fullstack-gonethttp-template/pkg/mailer"
+	"starter-kit-fullstack-gonethttp-template/pkg/telemetry"
+	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	outboxPollInterval = 5 * time.Second
+	outboxStaleAfter   = 10 * time.Minute // A worker holding an email this long is assumed dead
+	outboxMaxDelay     = time.Hour
+)
+
+var (
+	ErrEmailNotFound  = newError(KindNotFound, "email_not_found", "email not found")
+	ErrEmailNotFailed = newError(KindConflict, "email_not_failed", "only failed emails can be retried")
+)
+
+type outboxService struct {
+	repo      repository.OutboxRepository
+	transport mailer.EmailTransport
+	cfg       *config.Config
+	wake      chan struct{}
+}
+
+func NewOutboxService(repo repository.OutboxRepository, transport mailer.EmailTransport, cfg *config.Config) OutboxService {
+	return &outboxService{
+		repo:      repo,
+		transport: transport,
+		cfg:       cfg,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+func (s *outboxService) Enqueue(ctx context.Context, email *models.OutboxEmail) error {
+	ctx, span := tracer.Start(ctx, "OutboxService.Enqueue")
+	defer span.End()
+
+	if email.IdempotencyKey == "" {
+		email.IdempotencyKey = uuid.NewString()
+	}
+	email.Status = models.EmailStatusPending
+	email.NextAttemptAt = time.Now()
+	email.TraceParent = telemetry.TraceParent(ctx)
+
+	created, err := s.repo.Create(ctx, email)
+	if err != nil {
+		return err
+	}
+	if created {
+		s.notify()
+	}
+	return nil
+}
+
+func (s *outboxService) GetEmails(ctx context.Context, status string, page, limit int) (*utils.PaginationResult, error) {
+	ctx, span := tracer.Start(ctx, "OutboxService.GetEmails")
+	defer span.End()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = 10
+	}
+
+	emails, totalRows, err := s.repo.FindAll(ctx, status, &utils.PaginationScope{Page: page, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	result := utils.GetPaginationResult(totalRows, page, limit, emails)
+	return &result, nil
+}
+
+func (s *outboxService) Retry(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error) {
+	ctx, span := tracer.Start(ctx, "OutboxService.Retry")
+	defer span.End()
+
+	email, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrEmailNotFound
+	}
+	if email.Status != models.EmailStatusFailed {
+		return nil, ErrEmailNotFailed
+	}
+
+	email.Status = models.EmailStatusPending
+	email.Attempts = 0
+	email.NextAttemptAt = time.Now()
+	if err := s.repo.Update(ctx, email); err != nil {
+		return nil, err
+	}
+
+	s.notify()
+	return email, nil
+}
+
+// Run claims due emails and hands them to a pool of workers until ctx is cancelled
+func (s *outboxService) Run(ctx context.Context) {
+	workers := max(s.cfg.Email.Workers, 1)
+	batch := workers * 10
+
+	// Emails already handed to a worker are sent and their outcome saved even once ctx is
+	// cancelled, the ones still queued are requeued as stale on the next start
+	sendCtx := context.WithoutCancel(ctx)
+	jobs := make(chan models.OutboxEmail)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range jobs {
+				s.process(sendCtx, &email)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if released, err := s.repo.ReleaseStale(ctx, time.Now().Add(-outboxStaleAfter)); err != nil {
+			slog.ErrorContext(ctx, "Email outbox: release stale failed", "error", err)
+		} else if released > 0 {
+			slog.WarnContext(ctx, "Email outbox: requeued stale emails", "count", released)
+		}
+
+		claimed, err := s.repo.ClaimDue(ctx, time.Now(), batch)
+		if err != nil {
+			slog.ErrorContext(ctx, "Email outbox: claim failed", "error", err)
+		}
+
+		for _, email := range claimed {
+			select {
+			case jobs <- email:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// A full batch means more may be waiting
+		if len(claimed) == batch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// process sends one email and records the outcome, scheduling a retry or dead-lettering it.
+// The delivery span joins the trace of the request that queued the email.
+func (s *outboxService) process(ctx context.Context, email *models.OutboxEmail) {
+	if parent := telemetry.SpanContextFromTraceParent(email.TraceParent); parent.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+	}
+	ctx, span := tracer.Start(ctx, "outbox.deliver", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		attribute.String("email.id", email.ID.String()),
+		attribute.String("email.template", email.Template),
+		attribute.Int("email.attempt", email.Attempts+1),
+	))
+	defer span.End()
+
+	err := s.deliver(ctx, email)
+	email.Attempts++
+
+	if err == nil {
+		now := time.Now()
+		email.Status = models.EmailStatusSent
+		email.SentAt = &now
+		email.LastError = ""
+	} else {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		email.LastError = err.Error()
+		if email.Attempts >= s.cfg.Email.MaxAttempts {
+			email.Status = models.EmailStatusFailed
+			slog.ErrorContext(ctx, "Email outbox: giving up", "email_id", email.ID, "to", email.To, "attempts", email.Attempts, "error", err)
+		} else {
+			email.Status = models.EmailStatusPending
+			email.NextAttemptAt = time.Now().Add(s.backoff(email.Attempts))
+		}
+	}
+
+	if err := s.repo.Update(ctx, email); err != nil {
+		slog.ErrorContext(ctx, "Email outbox: saving failed", "email_id", email.ID, "error", err)
+	}
+}
+
+// backoff doubles the base delay after every failed attempt, capped at outboxMaxDelay
+func (s *outboxService) backoff(attempts int) time.Duration {
+	delay := time.Duration(s.cfg.Email.RetryBaseSeconds) * time.Second
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxDelay)
+}
+
+func (s *outboxService) deliver(ctx context.Context, email *models.OutboxEmail) error {
+	return s.transport.Send(&mailer.Message{
+		From:    mail.Address{Name: s.cfg.App.Name, Address: s.cfg.SMTP.From},
+		To:      []mail.Address{{Address: email.To}},
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+	})
+}
+
+func (s *outboxService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+	"starter-kit-fullstack-gonethttp-template/pkg/mailer"
+	"starter-kit-fullstack-gonethttp-template/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// fakeSMTP is an SMTP server on a free local port that keeps what it receives.
+// While reject is set it turns every recipient away, like a mailbox that is unavailable.
+// With hold set, it answers a message only once hold is closed.
+type fakeSMTP struct {
+	addr *net.TCPAddr
+	hold chan struct{}
+
+	mu       sync.Mutex
+	reject   bool
+	received []string // DATA of every accepted message
+	got      chan struct{}
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeSMTP{addr: ln.Addr().(*net.TCPAddr), got: make(chan struct{}, 16)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) setReject(reject bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = reject
+}
+
+func (s *fakeSMTP) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	reply := func(line string) {
+		rw.WriteString(line + "\r\n")
+		rw.Flush()
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			reject := s.reject
+			s.mu.Unlock()
+			if reject {
+				reply("550 mailbox unavailable")
+			} else {
+				reply("250 OK")
+			}
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := rw.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.received = append(s.received, data.String())
+			s.mu.Unlock()
+			if s.hold != nil {
+				<-s.hold
+			}
+			reply("250 queued")
+			s.got <- struct{}{}
+		case cmd == "RSET", cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func newTestOutbox(t *testing.T, smtp *fakeSMTP) (*outboxService, repository.OutboxRepository) {
+	t.Helper()
+	db := newTestDB(t, &models.OutboxEmail{})
+
+	cfg := &config.Config{}
+	cfg.App.Name = "Starter Kit"
+	cfg.SMTP.From = "noreply@example.com"
+	cfg.Email.Workers = 1
+	cfg.Email.MaxAttempts = 3
+	cfg.Email.RetryBaseSeconds = 30
+
+	repo := repository.NewOutboxRepository(db)
+	transport := &mailer.SMTPTransport{Host: "127.0.0.1", Port: smtp.addr.Port, Timeout: 5 * time.Second}
+	return NewOutboxService(repo, transport, cfg).(*outboxService), repo
+}
+
+// queue enqueues an email and claims it the way Run does, ready for process
+func queue(t *testing.T, s *outboxService, repo repository.OutboxRepository, to string) *models.OutboxEmail {
+	t.Helper()
+	ctx := context.Background()
+	email := &models.OutboxEmail{Template: "test", To: to, Subject: "Hello", Text: "Hello there"}
+	if err := s.Enqueue(ctx, email); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	return claim(t, repo, email.ID)
+}
+
+func claim(t *testing.T, repo repository.OutboxRepository, id uuid.UUID) *models.OutboxEmail {
+	t.Helper()
+	claimed, err := repo.ClaimDue(context.Background(), time.Now(), 10)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	for i := range claimed {
+		if claimed[i].ID == id {
+			return &claimed[i]
+		}
+	}
+	t.Fatalf("email %s was not due", id)
+	return nil
+}
+
+func reload(t *testing.T, repo repository.OutboxRepository, id uuid.UUID) *models.OutboxEmail {
+	t.Helper()
+	email, err := repo.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("find email: %v", err)
+	}
+	return email
+}
+
+func TestOutboxRunDeliversQueuedEmail(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	s, repo := newTestOutbox(t, smtp)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	email := &models.OutboxEmail{Template: "test", To: "jane@example.com", Subject: "Welcome", Text: "Hello Jane"}
+	if err := s.Enqueue(context.Background(), email); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	// Enqueue wakes the worker, no need to wait for the poll interval
+	select {
+	case <-smtp.got:
+	case <-time.After(3 * time.Second):
+		t.Fatal("email was not delivered")
+	}
+
+	msgs := smtp.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	for _, want := range []string{"To: <jane@example.com>", "Subject: Welcome", "Hello Jane"} {
+		if !strings.Contains(msgs[0], want) {
+			t.Errorf("message does not contain %q:\n%s", want, msgs[0])
+		}
+	}
+
+	// The outcome is saved right after the server accepted the message
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		got := reload(t, repo, email.ID)
+		if got.Status == models.EmailStatusSent {
+			if got.Attempts != 1 || got.SentAt == nil || got.LastError != "" {
+				t.Errorf("sent email has attempts=%d sentAt=%v lastError=%q", got.Attempts, got.SentAt, got.LastError)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status is %q, want %q", got.Status, models.EmailStatusSent)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOutboxRunFinishesSendingOnShutdown(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	smtp.hold = make(chan struct{})
+	s, repo := newTestOutbox(t, smtp)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	email := &models.OutboxEmail{Template: "test", To: "jane@example.com", Subject: "Welcome", Text: "Hello Jane"}
+	if err := s.Enqueue(context.Background(), email); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for len(smtp.messages()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("email was not sent")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Shut down while the server has yet to answer
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Run returned while an email was being sent")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(smtp.hold)
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return after the email was sent")
+	}
+	if got := reload(t, repo, email.ID); got.Status != models.EmailStatusSent {
+		t.Errorf("status is %q, want %q", got.Status, models.EmailStatusSent)
+	}
+}
+
+func TestOutboxEnqueueIsIdempotent(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	s, repo := newTestOutbox(t, smtp)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		email := &models.OutboxEmail{IdempotencyKey: "welcome:jane", To: "jane@example.com", Subject: "Welcome", Text: "Hello"}
+		if err := s.Enqueue(ctx, email); err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+	}
+
+	_, total, err := repo.FindAll(ctx, "", &utils.PaginationScope{Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("find all: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("queued %d emails, want 1", total)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	s := &outboxService{cfg: &config.Config{}}
+	s.cfg.Email.RetryBaseSeconds = 30
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{8, outboxMaxDelay},
+		{100, outboxMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestOutboxFailedDeliveryIsRetriedLater(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	smtp.setReject(true)
+	s, repo := newTestOutbox(t, smtp)
+
+	email := queue(t, s, repo, "gone@example.com")
+	before := time.Now()
+	s.process(context.Background(), email)
+
+	got := reload(t, repo, email.ID)
+	if got.Status != models.EmailStatusPending {
+		t.Fatalf("status is %q, want %q", got.Status, models.EmailStatusPending)
+	}
+	if got.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", got.Attempts)
+	}
+	if !strings.Contains(got.LastError, "550") {
+		t.Errorf("last error %q does not hold the server's answer", got.LastError)
+	}
+	// First retry after the base delay
+	if wait := got.NextAttemptAt.Sub(before); wait < 30*time.Second || wait > 31*time.Second {
+		t.Errorf("next attempt in %v, want 30s", wait)
+	}
+	if len(smtp.messages()) != 0 {
+		t.Error("a rejected email was recorded as received")
+	}
+
+	// Not due again before the delay is over
+	claimed, err := repo.ClaimDue(context.Background(), time.Now(), 10)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("claimed %d emails during the backoff", len(claimed))
+	}
+}
+
+func TestOutboxDeadLettersAfterMaxAttempts(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	smtp.setReject(true)
+	s, repo := newTestOutbox(t, smtp)
+	ctx := context.Background()
+
+	email := queue(t, s, repo, "gone@example.com")
+	for attempt := 1; attempt <= s.cfg.Email.MaxAttempts; attempt++ {
+		s.process(ctx, email)
+		got := reload(t, repo, email.ID)
+
+		want := models.EmailStatusPending
+		if attempt == s.cfg.Email.MaxAttempts {
+			want = models.EmailStatusFailed
+		}
+		if got.Status != want || got.Attempts != attempt {
+			t.Fatalf("after attempt %d: status=%q attempts=%d, want status=%q", attempt, got.Status, got.Attempts, want)
+		}
+		email = got
+	}
+
+	// A dead letter stays put, however long it waits
+	claimed, err := repo.ClaimDue(ctx, time.Now().Add(24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("claimed %d dead-lettered emails", len(claimed))
+	}
+}
+
+func TestOutboxRetrySendsDeadLetterAgain(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	smtp.setReject(true)
+	s, repo := newTestOutbox(t, smtp)
+	ctx := context.Background()
+
+	email := queue(t, s, repo, "back@example.com")
+	for i := 0; i < s.cfg.Email.MaxAttempts; i++ {
+		s.process(ctx, email)
+	}
+
+	// The mailbox is back, an admin retries
+	smtp.setReject(false)
+	retried, err := s.Retry(ctx, email.ID)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if retried.Status != models.EmailStatusPending || retried.Attempts != 0 {
+		t.Fatalf("retried email has status=%q attempts=%d", retried.Status, retried.Attempts)
+	}
+
+	s.process(ctx, claim(t, repo, email.ID))
+
+	got := reload(t, repo, email.ID)
+	if got.Status != models.EmailStatusSent || got.Attempts != 1 {
+		t.Errorf("status=%q attempts=%d, want sent after 1 attempt", got.Status, got.Attempts)
+	}
+	if len(smtp.messages()) != 1 {
+		t.Errorf("got %d messages, want 1", len(smtp.messages()))
+	}
+}
+
+func TestOutboxRetryOnlyFailedEmails(t *testing.T) {
+	smtp := newFakeSMTP(t)
+	s, repo := newTestOutbox(t, smtp)
+	ctx := context.Background()
+
+	email := &models.OutboxEmail{To: "jane@example.com", Subject: "Hello", Text: "Hello"}
+	if err := s.Enqueue(ctx, email); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := s.Retry(ctx, email.ID); !errors.Is(err, ErrEmailNotFailed) {
+		t.Errorf("retry of a pending email: got %v, want ErrEmailNotFailed", err)
+	}
+
+	s.process(ctx, claim(t, repo, email.ID))
+	if _, err := s.Retry(ctx, email.ID); !errors.Is(err, ErrEmailNotFailed) {
+		t.Errorf("retry of a sent email: got %v, want ErrEmailNotFailed", err)
+	}
+
+	if _, err := s.Retry(ctx, uuid.New()); !errors.Is(err, ErrEmailNotFound) {
+		t.Errorf("retry of an unknown email: got %v, want ErrEmailNotFound", err)
+	}
+}
\ No newline at end of file
@@ -1,17 +1,26 @@
 package services
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/pkg/telemetry"
 	"starter-kit-fullstack-gonethttp-template/pkg/utils"
 
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/google/uuid"
 )
 
+// Exported service methods open a span named after the interface, e.g. "AuthService.Login"
+var tracer = telemetry.Tracer("services")
+
 // DTOs
 type RegisterRequest struct {
-	Name     string `json:"name" validate:"required"`
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email,unique_email"`
+	Password string `json:"password" validate:"required,password"`
 }
 
 type CreateUserRequest struct {
@@ -20,9 +29,85 @@ type CreateUserRequest struct {
 }
 
 type UpdateUserRequest struct {
-	Name     string `json:"name" validate:"omitempty"`
-	Email    string `json:"email" validate:"omitempty,email"`
-	Password string `json:"password" validate:"omitempty,min=8"`
+	Name     string  `json:"name" validate:"omitempty,max=100"`
+	Email    string  `json:"email" validate:"omitempty,email"`
+	Password string  `json:"password" validate:"omitempty,password"`
+	Role     string  `json:"role" validate:"omitempty,oneof=user admin"`
+	Locale   *string `json:"locale" validate:"omitempty,locale"` // "" goes back to the browser's language
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write apikeys:manage"`
+	ExpiresInDays int      `json:"expiresInDays" validate:"omitempty,min=1,max=3650"` // 0 = never expires
+}
+
+type UpdateProfileRequest struct {
+	Name   string  `json:"name" validate:"required,max=100"`
+	Locale *string `json:"locale" validate:"omitempty,locale"` // "" goes back to the browser's language
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,password,nefield=CurrentPassword"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// UpdateNotificationsRequest toggles security emails; omitted fields keep their value
+type UpdateNotificationsRequest struct {
+	NewLogin       *bool `json:"newLogin"`
+	AccountChanges *bool `json:"accountChanges"`
+	AdminChanges   *bool `json:"adminChanges"`
+}
+
+type RenamePasskeyRequest struct {
+	Name string `json:"name" validate:"required,max=64"`
+}
+
+type PasskeyRequiredRequest struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+// ClientInfo identifies the client behind a request, for security notices
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// AccountExport is the personal data archive returned by GET /v1/me/export
+type AccountExport struct {
+	ExportedAt time.Time                   `json:"exportedAt"`
+	Profile    *models.User                `json:"profile"`
+	Sessions   []SessionExport             `json:"sessions"`
+	APIKeys    []models.APIKey             `json:"apiKeys"`
+	Devices    []models.Device             `json:"devices"`
+	Passkeys   []models.WebAuthnCredential `json:"passkeys"`
+	AuditLog   []models.AuditEntry         `json:"auditLog"` // Latest first
+}
+
+// SessionExport describes an active refresh token without exposing the token itself
+type SessionExport struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Expires   time.Time `json:"expires"`
+}
+
+type InviteUserRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=user admin"`
+}
+
+type AcceptInviteRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,password"`
 }
 
 type UserQueryOptions struct {
@@ -34,30 +119,178 @@ type UserQueryOptions struct {
 	RoleFilter   string
 }
 
+// StatsQuery is the period the stats cover, as given by the client. From and To are
+// dates ("2026-01-31", To inclusive) or RFC 3339 times; they default to the last 30
+// days. Interval groups the signups by "day" (default), "week" or "month".
+type StatsQuery struct {
+	From     string
+	To       string
+	Interval string
+}
+
+type Stats struct {
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"` // Exclusive
+	Interval    string       `json:"interval"`
+	Users       UserStats    `json:"users"`
+	Signups     SignupStats  `json:"signups"`
+	Sessions    SessionStats `json:"sessions"`
+	Devices     DeviceStats  `json:"devices"`
+	GeneratedAt time.Time    `json:"generatedAt"` // Stats are cached briefly, this tells how fresh they are
+}
+
+// UserStats counts every account, regardless of the period
+type UserStats struct {
+	Total      int64            `json:"total"`
+	ByRole     map[string]int64 `json:"byRole"`
+	Verified   int64            `json:"verified"`
+	Unverified int64            `json:"unverified"`
+}
+
+type SignupStats struct {
+	Total   int64         `json:"total"`
+	Buckets []StatsBucket `json:"buckets"` // One per interval, empty ones included
+}
+
+type StatsBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// SessionStats counts the refresh tokens in use right now, regardless of the period
+type SessionStats struct {
+	Active int64 `json:"active"`
+	Users  int64 `json:"users"`
+}
+
+// DeviceStats comes from the known devices, which only remember their latest sign-in:
+// earlier sign-ins on a device are not counted
+type DeviceStats struct {
+	Users       int64          `json:"users"`       // Distinct users with a device last signed in from during the period
+	LastSignIns []DeviceSignIn `json:"lastSignIns"` // Devices by their latest sign-in, newest first
+}
+
+type DeviceSignIn struct {
+	UserID    uuid.UUID `json:"userId"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	UserAgent string    `json:"userAgent"`
+	IP        string    `json:"ip"`
+	At        time.Time `json:"at"`
+}
+
 // Interfaces
 
 type AuthService interface {
-	Login(email, password string) (*models.User, map[string]interface{}, error)
-	Register(req RegisterRequest) (*models.User, map[string]interface{}, error)
-	RefreshAuth(refreshToken string) (map[string]interface{}, error)
-	Logout(refreshToken string) error
+	Login(ctx context.Context, email, password string, client ClientInfo) (*models.User, map[string]interface{}, error)
+	Register(ctx context.Context, req RegisterRequest) (*models.User, map[string]interface{}, error)
+	RefreshAuth(ctx context.Context, refreshToken string) (map[string]interface{}, error)
+	Logout(ctx context.Context, refreshToken string) error
 	
-	ForgotPassword(email string) error
-	ResetPassword(token, newPassword string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+
+	// Check a link from an email without using it up: ErrTokenExpired, ErrInvalidToken or nil
+	CheckResetPasswordToken(ctx context.Context, token string) error
+	CheckVerifyEmailToken(ctx context.Context, token string) error
+
+	// Passwordless sign-in
+	RequestMagicLink(ctx context.Context, email string) error
+	LoginWithMagicLink(ctx context.Context, token string, client ClientInfo) (*models.User, map[string]interface{}, error)
 	
-	VerifyEmail(token string) error
+	VerifyEmail(ctx context.Context, token string) error
+
+	// Self-service
+	ChangePassword(ctx context.Context, userID uuid.UUID, req ChangePasswordRequest) (map[string]interface{}, error)
+	RequestEmailChange(ctx context.Context, userID uuid.UUID, req ChangeEmailRequest) error
+}
+
+type WebAuthnService interface {
+	// Ceremonies: begin returns the browser options and a session ID the finish step must send back
+	BeginRegistration(ctx context.Context, userID uuid.UUID) (*protocol.CredentialCreation, uuid.UUID, error)
+	FinishRegistration(ctx context.Context, userID, sessionID uuid.UUID, name string, body io.Reader) (*models.WebAuthnCredential, error)
+	// BeginLogin starts a passwordless sign-in, or the second factor when loginToken comes from a PasskeyRequiredError
+	BeginLogin(ctx context.Context, loginToken string) (*protocol.CredentialAssertion, uuid.UUID, error)
+	FinishLogin(ctx context.Context, sessionID uuid.UUID, body io.Reader, client ClientInfo) (*models.User, map[string]interface{}, error)
+
+	// Management
+	ListCredentials(ctx context.Context, userID uuid.UUID) ([]models.WebAuthnCredential, error)
+	RenameCredential(ctx context.Context, userID, id uuid.UUID, name string) (*models.WebAuthnCredential, error)
+	DeleteCredential(ctx context.Context, userID, id uuid.UUID) error
+	SetPasskeyRequired(ctx context.Context, userID uuid.UUID, required bool) (*models.User, error)
 }
 
 type UserService interface {
-	CreateUser(req CreateUserRequest) (*models.User, error)
-	GetUserByID(id uuid.UUID) (*models.User, error)
-	GetUsers(options UserQueryOptions) (*utils.PaginationResult, error)
-	UpdateUser(id uuid.UUID, req UpdateUserRequest) (*models.User, error)
-	DeleteUser(id uuid.UUID) error
+	CreateUser(ctx context.Context, req CreateUserRequest) (*models.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUsers(ctx context.Context, options UserQueryOptions) (*utils.PaginationResult, error)
+	// actorID is the authenticated user performing the change, used for the admin guardrails
+	UpdateUser(ctx context.Context, actorID, id uuid.UUID, req UpdateUserRequest) (*models.User, error)
+	DeleteUser(ctx context.Context, actorID, id uuid.UUID) error
+}
+
+type APIKeyService interface {
+	// CreateKey returns the stored key and the plain-text secret, which is never retrievable again
+	CreateKey(ctx context.Context, userID uuid.UUID, req CreateAPIKeyRequest) (*models.APIKey, string, error)
+	ListKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	RevokeKey(ctx context.Context, userID, keyID uuid.UUID) error
+	Authenticate(ctx context.Context, rawKey string) (*models.APIKey, error)
+}
+
+type InvitationService interface {
+	Invite(ctx context.Context, inviterID uuid.UUID, req InviteUserRequest) (*models.Invitation, error)
+	ListOpen(ctx context.Context) ([]models.Invitation, error)
+	Resend(ctx context.Context, id uuid.UUID) (*models.Invitation, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	// GetPending validates an invite token and returns the invitation it belongs to
+	GetPending(ctx context.Context, token string) (*models.Invitation, error)
+	Accept(ctx context.Context, token string, req AcceptInviteRequest) (*models.User, map[string]interface{}, error)
+}
+
+type AccountService interface {
+	ExportData(ctx context.Context, userID uuid.UUID) (*AccountExport, error)
+	// ScheduleDeletion returns the purge date, or nil when the account was deleted immediately
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, req DeleteAccountRequest) (*time.Time, error)
+	// PurgeScheduledDeletions deletes every account whose grace period is over
+	PurgeScheduledDeletions(ctx context.Context) (int, error)
+	// Purge deletes an account right away, without the checks and email of ScheduleDeletion:
+	// sessions, API keys, passkeys, devices, the audit log and open invitations sent by the
+	// user go with it
+	Purge(ctx context.Context, userID uuid.UUID) error
+}
+
+type NotificationService interface {
+	UpdatePreferences(ctx context.Context, userID uuid.UUID, req UpdateNotificationsRequest) (*models.User, error)
+	// CheckSecureAccountToken validates a "this wasn't me" link without using it up
+	CheckSecureAccountToken(ctx context.Context, token string) (*models.User, error)
+	// SecureAccount signs the user out everywhere: sessions, pending links, API keys, passkeys and known devices
+	SecureAccount(ctx context.Context, token string) error
+}
+
+type OutboxService interface {
+	// Enqueue stores an email for background delivery
+	Enqueue(ctx context.Context, email *models.OutboxEmail) error
+	GetEmails(ctx context.Context, status string, page, limit int) (*utils.PaginationResult, error)
+	// Retry puts a dead-lettered email back in the queue
+	Retry(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error)
+	// Run delivers queued emails until ctx is cancelled, then returns once the emails being
+	// sent are done
+	Run(ctx context.Context)
+}
+
+type StatsService interface {
+	GetStats(ctx context.Context, query StatsQuery) (*Stats, error)
 }
 
 type EmailService interface {
-	SendEmail(to, subject, body string) error
-	SendResetPasswordEmail(to, token string) error
-	SendVerificationEmail(to, token string) error
+	// SendTemplate renders an email template for a locale ("" = the locale of the request
+	// in ctx) and queues it. Sending again with the same idempotency key is a no-op; ""
+	// never deduplicates. The other methods take the recipient's locale the same way.
+	SendTemplate(ctx context.Context, idempotencyKey, to, locale, name string, data map[string]interface{}) error
+	SendResetPasswordEmail(ctx context.Context, to, locale, token string) error
+	SendVerificationEmail(ctx context.Context, to, locale, token string) error
+	SendInvitationEmail(ctx context.Context, to, locale, token string) error
+	SendMagicLinkEmail(ctx context.Context, to, locale, token string) error
+	// SendAccountDeletionEmail confirms a self-deletion; a zero purgeAt means the account is already gone
+	SendAccountDeletionEmail(ctx context.Context, to, locale string, purgeAt time.Time) error
 }
\ No newline at end of file
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	statsCacheTTL     = time.Minute
+	statsDefaultRange = 30 * 24 * time.Hour
+	statsMaxRange     = 2 * 366 * 24 * time.Hour
+	statsLastSignIns  = 10
+)
+
+var (
+	ErrInvalidStatsRange    = newError(KindValidation, "invalid_stats_range", "from and to must be dates (2006-01-02) or RFC 3339 times, with from before to")
+	ErrStatsRangeTooLong    = newError(KindValidation, "stats_range_too_long", "the period may span at most two years")
+	ErrInvalidStatsInterval = newError(KindValidation, "invalid_stats_interval", "interval must be day, week or month")
+)
+
+type statsService struct {
+	userRepo   repository.UserRepository
+	tokenRepo  repository.TokenRepository
+	deviceRepo repository.DeviceRepository
+
+	// Keyed by the query as given, so "the last 30 days" is served from the cache too
+	mu    sync.Mutex
+	cache map[StatsQuery]*Stats
+}
+
+func NewStatsService(uRepo repository.UserRepository, tRepo repository.TokenRepository, dRepo repository.DeviceRepository) StatsService {
+	return &statsService{
+		userRepo:   uRepo,
+		tokenRepo:  tRepo,
+		deviceRepo: dRepo,
+		cache:      make(map[StatsQuery]*Stats),
+	}
+}
+
+// GetStats aggregates over the whole user base, so results are kept for statsCacheTTL
+// (per instance) rather than recomputed for every dashboard view
+func (s *statsService) GetStats(ctx context.Context, query StatsQuery) (*Stats, error) {
+	ctx, span := tracer.Start(ctx, "StatsService.GetStats")
+	defer span.End()
+
+	now := time.Now()
+	if stats := s.cached(query, now); stats != nil {
+		return stats, nil
+	}
+
+	from, to, interval, err := parseStatsQuery(query, now)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{From: from, To: to, Interval: interval, GeneratedAt: now}
+
+	byRole, err := s.userRepo.CountGroupedByRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Users.ByRole = map[string]int64{models.RoleUser: 0, models.RoleAdmin: 0}
+	for role, count := range byRole {
+		stats.Users.ByRole[role] = count
+		stats.Users.Total += count
+	}
+	if stats.Users.Verified, err = s.userRepo.CountVerified(ctx); err != nil {
+		return nil, err
+	}
+	stats.Users.Unverified = stats.Users.Total - stats.Users.Verified
+
+	signups, err := s.userRepo.CountCreatedPerBucket(ctx, from, to, interval)
+	if err != nil {
+		return nil, err
+	}
+	stats.Signups = bucketSignups(signups, from, to, interval)
+
+	if stats.Sessions.Active, stats.Sessions.Users, err = s.tokenRepo.CountActive(ctx, models.TokenTypeRefresh, now); err != nil {
+		return nil, err
+	}
+
+	if stats.Devices.Users, err = s.deviceRepo.CountUsersSeenBetween(ctx, from, to); err != nil {
+		return nil, err
+	}
+	if stats.Devices.LastSignIns, err = s.lastSignIns(ctx, from, to); err != nil {
+		return nil, err
+	}
+
+	s.store(query, stats, now)
+	return stats, nil
+}
+
+// lastSignIns lists the devices signed in from last, with the name and email of their user
+func (s *statsService) lastSignIns(ctx context.Context, from, to time.Time) ([]DeviceSignIn, error) {
+	devices, err := s.deviceRepo.FindSeenBetween(ctx, from, to, statsLastSignIns)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(devices))
+	for _, device := range devices {
+		ids = append(ids, device.UserID)
+	}
+	users, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	signIns := make([]DeviceSignIn, 0, len(devices))
+	for _, device := range devices {
+		user, ok := byID[device.UserID]
+		if !ok {
+			continue
+		}
+		signIns = append(signIns, DeviceSignIn{
+			UserID:    user.ID,
+			Name:      user.Name,
+			Email:     user.Email,
+			UserAgent: device.UserAgent,
+			IP:        device.LastIP,
+			At:        device.LastSeenAt,
+		})
+	}
+	return signIns, nil
+}
+
+func (s *statsService) cached(query StatsQuery, now time.Time) *Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.cache[query]
+	if !ok || now.Sub(stats.GeneratedAt) >= statsCacheTTL {
+		return nil
+	}
+	return stats
+}
+
+// store caches stats and drops the expired entries, so odd ranges don't pile up
+func (s *statsService) store(query StatsQuery, stats *Stats, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.cache {
+		if now.Sub(entry.GeneratedAt) >= statsCacheTTL {
+			delete(s.cache, key)
+		}
+	}
+	s.cache[query] = stats
+}
+
+// parseStatsQuery resolves the period of query, in UTC
+func parseStatsQuery(query StatsQuery, now time.Time) (from, to time.Time, interval string, err error) {
+	interval = strings.ToLower(strings.TrimSpace(query.Interval))
+	switch interval {
+	case "":
+		interval = "day"
+	case "day", "week", "month":
+	default:
+		return from, to, "", ErrInvalidStatsInterval
+	}
+
+	to = now.UTC()
+	if query.To != "" {
+		if to, err = parseStatsTime(query.To, true); err != nil {
+			return from, to, "", err
+		}
+	}
+	from = to.Add(-statsDefaultRange)
+	if query.From != "" {
+		if from, err = parseStatsTime(query.From, false); err != nil {
+			return from, to, "", err
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, "", ErrInvalidStatsRange
+	}
+	if to.Sub(from) > statsMaxRange {
+		return from, to, "", ErrStatsRangeTooLong
+	}
+	return from, to, interval, nil
+}
+
+// parseStatsTime reads a date or an RFC 3339 time. A date as the end of the period
+// includes that day.
+func parseStatsTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, ErrInvalidStatsRange
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// bucketSignups lays the counted buckets out on every interval from the bucket holding
+// from up to to, so a chart gets a bar for the empty ones too
+func bucketSignups(counts []repository.BucketCount, from, to time.Time, interval string) SignupStats {
+	var buckets []StatsBucket
+	index := make(map[time.Time]int)
+	for start := bucketStart(from, interval); start.Before(to); start = nextBucket(start, interval) {
+		index[start] = len(buckets)
+		buckets = append(buckets, StatsBucket{Start: start})
+	}
+
+	var total int64
+	for _, count := range counts {
+		total += count.Count
+		if i, ok := index[count.Start]; ok {
+			buckets[i].Count = count.Count
+		}
+	}
+	return SignupStats{Total: total, Buckets: buckets}
+}
+
+// bucketStart truncates t to its day, its week (starting Monday) or its month, in UTC
+func bucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
\ No newline at end of file
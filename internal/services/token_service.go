@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"time"
 
 	"starter-kit-fullstack-gonethttp-template/config"
@@ -21,7 +22,10 @@ func NewTokenService(repo repository.TokenRepository, cfg *config.Config) *Token
 }
 
 // GenerateAuthTokens creates access and refresh tokens
-func (s *TokenService) GenerateAuthTokens(userID uuid.UUID) (map[string]interface{}, error) {
+func (s *TokenService) GenerateAuthTokens(ctx context.Context, userID uuid.UUID) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "TokenService.GenerateAuthTokens")
+	defer span.End()
+
 	// Access Token
 	accessDur := time.Duration(s.cfg.JWT.AccessExpirationMinutes) * time.Minute
 	accessToken, accessExp, err := utils.GenerateToken(userID, accessDur, "access", s.cfg.JWT.Secret)
@@ -37,7 +41,7 @@ func (s *TokenService) GenerateAuthTokens(userID uuid.UUID) (map[string]interfac
 	}
 
 	// Save Refresh Token to DB
-	err = s.SaveToken(refreshToken, userID.String(), refreshExp, models.TokenTypeRefresh)
+	err = s.SaveToken(ctx, refreshToken, userID.String(), refreshExp, models.TokenTypeRefresh)
 	if err != nil {
 		return nil, err
 	}
@@ -54,21 +58,27 @@ func (s *TokenService) GenerateAuthTokens(userID uuid.UUID) (map[string]interfac
 	}, nil
 }
 
-func (s *TokenService) SaveToken(token, userID string, expires time.Time, tokenType string) error {
+func (s *TokenService) SaveToken(ctx context.Context, token, userID string, expires time.Time, tokenType string) error {
+	ctx, span := tracer.Start(ctx, "TokenService.SaveToken")
+	defer span.End()
+
 	tokenModel := &models.Token{
 		Token:   token,
 		UserID:  userID,
 		Expires: expires,
 		Type:    tokenType,
 	}
-	return s.repo.Create(tokenModel)
+	return s.repo.Create(ctx, tokenModel)
 }
 
-func (s *TokenService) VerifyToken(token string, tokenType string) (*models.Token, error) {
+func (s *TokenService) VerifyToken(ctx context.Context, token string, tokenType string) (*models.Token, error) {
+	ctx, span := tracer.Start(ctx, "TokenService.VerifyToken")
+	defer span.End()
+
 	// Verify signature
 	if _, err := utils.ValidateToken(token, s.cfg.JWT.Secret); err != nil {
 		return nil, err
 	}
 	// Verify existence in DB
-	return s.repo.FindByToken(token, tokenType)
+	return s.repo.FindByToken(ctx, token, tokenType)
 }
\ No newline at end of file
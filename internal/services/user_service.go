@@ -1,7 +1,8 @@
 package services
 
 import (
-	"errors"
+	"context"
+	"time"
 
 	"starter-kit-fullstack-gonethttp-template/internal/models"
 	"starter-kit-fullstack-gonethttp-template/internal/repository"
@@ -10,17 +11,31 @@ import (
 	"github.com/google/uuid"
 )
 
+// Admin guardrails, returned by UpdateUser and DeleteUser
+var (
+	ErrInvalidRole  = newError(KindValidation, "invalid_role", "invalid role")
+	ErrSelfDemotion = newError(KindForbidden, "self_demotion", "you cannot change your own role")
+	ErrSelfDeletion = newError(KindForbidden, "self_deletion", "you cannot delete your own account here")
+	ErrLastAdmin    = newError(KindConflict, "last_admin", "the last admin cannot be demoted or deleted")
+)
+
 type userService struct {
-	repo repository.UserRepository
+	repo      repository.UserRepository
+	tokenRepo repository.TokenRepository
+	accounts  AccountService
+	events    *EventBus
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{repo: repo}
+func NewUserService(repo repository.UserRepository, tokenRepo repository.TokenRepository, accounts AccountService, events *EventBus) UserService {
+	return &userService{repo: repo, tokenRepo: tokenRepo, accounts: accounts, events: events}
 }
 
-func (s *userService) CreateUser(req CreateUserRequest) (*models.User, error) {
-	if exists, _ := s.repo.ExistsByEmail(req.Email); exists {
-		return nil, errors.New("email already taken")
+func (s *userService) CreateUser(ctx context.Context, req CreateUserRequest) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	if exists, _ := s.repo.ExistsByEmail(ctx, req.Email); exists {
+		return nil, ErrEmailTaken
 	}
 
 	user := &models.User{
@@ -30,17 +45,27 @@ func (s *userService) CreateUser(req CreateUserRequest) (*models.User, error) {
 		Role:     req.Role,
 	}
 
-	if err := s.repo.Create(user); err != nil {
+	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *userService) GetUserByID(id uuid.UUID) (*models.User, error) {
-	return s.repo.FindByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
 }
 
-func (s *userService) GetUsers(opts UserQueryOptions) (*utils.PaginationResult, error) {
+func (s *userService) GetUsers(ctx context.Context, opts UserQueryOptions) (*utils.PaginationResult, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUsers")
+	defer span.End()
+
 	// 1. Prepare Pagination Scope
 	if opts.Page < 1 {
 		opts.Page = 1
@@ -57,7 +82,7 @@ func (s *userService) GetUsers(opts UserQueryOptions) (*utils.PaginationResult,
 
 	// 2. Prepare Filters (Strict)
 	filters := make(map[string]interface{})
-	if opts.RoleFilter == "user" || opts.RoleFilter == "admin" {
+	if models.IsValidRole(opts.RoleFilter) {
 		filters["role"] = opts.RoleFilter
 	}
 
@@ -79,7 +104,7 @@ func (s *userService) GetUsers(opts UserQueryOptions) (*utils.PaginationResult,
 	}
 
 	// 4. Query Repository
-	users, totalRows, err := s.repo.FindAll(filters, opts.Search, searchFields, paginationScope)
+	users, totalRows, err := s.repo.FindAll(ctx, filters, opts.Search, searchFields, paginationScope)
 	if err != nil {
 		return nil, err
 	}
@@ -88,34 +113,121 @@ func (s *userService) GetUsers(opts UserQueryOptions) (*utils.PaginationResult,
 	return &result, nil
 }
 
-func (s *userService) UpdateUser(id uuid.UUID, req UpdateUserRequest) (*models.User, error) {
-	user, err := s.repo.FindByID(id)
+func (s *userService) UpdateUser(ctx context.Context, actorID, id uuid.UUID, req UpdateUserRequest) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
+	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
+	}
+
+	oldEmail := user.Email
+	wasAdmin := user.Role == models.RoleAdmin
+	var changes []string
+
+	roleChanged := req.Role != "" && req.Role != user.Role
+	if roleChanged {
+		if err := s.checkRoleChange(actorID, user, req.Role); err != nil {
+			return nil, err
+		}
+		user.Role = req.Role
+		changes = append(changes, "role")
 	}
 
 	if req.Email != "" && req.Email != user.Email {
-		if exists, _ := s.repo.ExistsByEmail(req.Email); exists {
-			return nil, errors.New("email already taken")
+		if exists, _ := s.repo.ExistsByEmail(ctx, req.Email); exists {
+			return nil, ErrEmailTaken
 		}
 		user.Email = req.Email
+		changes = append(changes, "email")
 	}
-	if req.Name != "" {
+	if req.Name != "" && req.Name != user.Name {
 		user.Name = req.Name
+		changes = append(changes, "name")
 	}
 	if req.Password != "" {
 		user.Password = req.Password
+		changes = append(changes, "password")
+	}
+	// Not a security relevant change, so it is left out of the notice
+	if req.Locale != nil {
+		user.Locale = *req.Locale
 	}
 
-	if err := s.repo.Update(user); err != nil {
+	// A demoted admin is saved only while another admin remains
+	if roleChanged && wasAdmin {
+		if err := updateUnlessLastAdmin(ctx, s.repo, user); err != nil {
+			return nil, err
+		}
+	} else if err := s.repo.Update(ctx, user); err != nil {
 		return nil, err
 	}
+
+	// Force the user to sign in again so every session picks up the new role
+	if roleChanged {
+		if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypeRefresh); err != nil {
+			return nil, err
+		}
+	}
+
+	// Users editing themselves (PATCH /v1/me) are not notified
+	if actorID != id && len(changes) > 0 {
+		event := Event{Type: EventAdminUpdatedAccount, User: user, Changes: changes}
+		if user.Email != oldEmail {
+			event.OldEmail, event.NewEmail = oldEmail, user.Email
+		}
+		s.events.Publish(ctx, event)
+	}
 	return user, nil
 }
 
-func (s *userService) DeleteUser(id uuid.UUID) error {
-	if _, err := s.repo.FindByID(id); err != nil {
-		return errors.New("user not found")
+func (s *userService) DeleteUser(ctx context.Context, actorID, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	if actorID == id {
+		return ErrSelfDeletion
+	}
+	// Scheduling the deletion first takes the admin out of the count in the same transaction
+	// that checks another one remains; if the purge fails, the scheduled purge finishes it
+	if user.Role == models.RoleAdmin {
+		now := time.Now()
+		user.DeletionScheduledAt = &now
+		if err := updateUnlessLastAdmin(ctx, s.repo, user); err != nil {
+			return err
+		}
+	}
+
+	// Deleted like a purged account, so nothing the user left behind still signs anyone in
+	return s.accounts.Purge(ctx, id)
+}
+
+// checkRoleChange applies the guardrails for changing user's role to newRole
+func (s *userService) checkRoleChange(actorID uuid.UUID, user *models.User, newRole string) error {
+	if !models.IsValidRole(newRole) {
+		return ErrInvalidRole
+	}
+	if actorID == user.ID {
+		return ErrSelfDemotion
+	}
+	return nil
+}
+
+// updateUnlessLastAdmin saves a change that takes an admin out of the active ones, unless
+// no other admin would be left
+func updateUnlessLastAdmin(ctx context.Context, repo repository.UserRepository, user *models.User) error {
+	saved, err := repo.UpdateUnlessLastAdmin(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrLastAdmin
 	}
-	return s.repo.Delete(id)
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+func TestLastAdminGuardrails(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, &models.User{}, &models.Token{}, &models.APIKey{}, &models.Invitation{}, &models.Device{}, &models.WebAuthnCredential{}, &models.AuditEntry{})
+	users := repository.NewUserRepository(db)
+	tokens := repository.NewTokenRepository(db)
+	accounts := NewAccountService(users, tokens, repository.NewAPIKeyRepository(db), repository.NewDeviceRepository(db),
+		repository.NewWebAuthnRepository(db), repository.NewInvitationRepository(db), repository.NewAuditRepository(db), stubEmails{}, &config.Config{})
+	service := NewUserService(users, tokens, accounts, nil)
+
+	// leaving is an admin on their way out, which leaves staying as the last one
+	purgeAt := time.Now().Add(time.Hour)
+	leaving := &models.User{Name: "Jane", Email: "jane@example.com", Password: "password1", Role: models.RoleAdmin, DeletionScheduledAt: &purgeAt}
+	staying := &models.User{Name: "John", Email: "john@example.com", Password: "password1", Role: models.RoleAdmin}
+	for _, admin := range []*models.User{leaving, staying} {
+		if err := users.Create(ctx, admin); err != nil {
+			t.Fatalf("create admin: %v", err)
+		}
+	}
+	actorID := uuid.New()
+
+	if _, err := service.UpdateUser(ctx, actorID, staying.ID, UpdateUserRequest{Role: models.RoleUser}); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("demoting the last admin: %v, want %v", err, ErrLastAdmin)
+	}
+	if err := service.DeleteUser(ctx, actorID, staying.ID); !errors.Is(err, ErrLastAdmin) {
+		t.Errorf("deleting the last admin: %v, want %v", err, ErrLastAdmin)
+	}
+	if user, err := users.FindByID(ctx, staying.ID); err != nil || user.Role != models.RoleAdmin || user.DeletionScheduledAt != nil {
+		t.Fatalf("the last admin was changed: %+v (%v)", user, err)
+	}
+
+	// The admin on their way out can go, staying remains
+	if _, err := service.UpdateUser(ctx, actorID, leaving.ID, UpdateUserRequest{Role: models.RoleUser}); err != nil {
+		t.Errorf("demoting an admin scheduled for deletion: %v", err)
+	}
+	if err := service.DeleteUser(ctx, actorID, leaving.ID); err != nil {
+		t.Errorf("deleting an admin scheduled for deletion: %v", err)
+	}
+}
\ No newline at end of file
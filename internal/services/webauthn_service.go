@@ -0,0 +1,427 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// webAuthnSessionTTL bounds how long the browser prompt may stay open
+const webAuthnSessionTTL = 5 * time.Minute
+
+var (
+	ErrInvalidPasskeySession = newError(KindValidation, "invalid_passkey_session", "the passkey request expired, please try again")
+	ErrPasskeyVerification   = newError(KindUnauthorized, "passkey_verification_failed", "passkey verification failed")
+	ErrPasskeyCloned         = newError(KindUnauthorized, "passkey_cloned", "this passkey reported an unexpected sign counter and may have been cloned, it can no longer be used")
+	ErrPasskeyNotFound       = newError(KindNotFound, "passkey_not_found", "passkey not found")
+	ErrNoPasskeys            = newError(KindValidation, "no_passkeys", "register a passkey before requiring one")
+)
+
+// PasskeyRequiredError is returned by password and magic-link sign-in when the account requires a passkey
+// as a second factor. Token is exchanged at POST /v1/auth/webauthn/login/begin.
+type PasskeyRequiredError struct {
+	Token string
+}
+
+func (e *PasskeyRequiredError) Error() string {
+	return "passkey required"
+}
+
+type webAuthnService struct {
+	webAuthn     *webauthn.WebAuthn
+	repo         repository.WebAuthnRepository
+	userRepo     repository.UserRepository
+	tokenRepo    repository.TokenRepository
+	tokenService *TokenService
+	events       *EventBus
+}
+
+func NewWebAuthnService(repo repository.WebAuthnRepository, uRepo repository.UserRepository, tRepo repository.TokenRepository, tService *TokenService, events *EventBus, cfg *config.Config) (WebAuthnService, error) {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthn.RPID,
+		RPDisplayName: cfg.App.Name,
+		RPOrigins:     cfg.WebAuthn.Origins,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: %w", err)
+	}
+
+	return &webAuthnService{
+		webAuthn:     w,
+		repo:         repo,
+		userRepo:     uRepo,
+		tokenRepo:    tRepo,
+		tokenService: tService,
+		events:       events,
+	}, nil
+}
+
+func (s *webAuthnService) BeginRegistration(ctx context.Context, userID uuid.UUID) (*protocol.CredentialCreation, uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.BeginRegistration")
+	defer span.End()
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	// Discoverable credentials (passkeys) so they also work without typing an email
+	creation, data, err := s.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	sessionID, err := s.saveSession(ctx, &userID, models.WebAuthnCeremonyRegistration, data)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	return creation, sessionID, nil
+}
+
+func (s *webAuthnService) FinishRegistration(ctx context.Context, userID, sessionID uuid.UUID, name string, body io.Reader) (*models.WebAuthnCredential, error) {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.FinishRegistration")
+	defer span.End()
+
+	session, data, err := s.takeSession(ctx, sessionID, models.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID == nil || *session.UserID != userID {
+		return nil, ErrInvalidPasskeySession
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		return nil, ErrPasskeyVerification
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webAuthn.CreateCredential(user, *data, parsed)
+	if err != nil {
+		return nil, ErrPasskeyVerification
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(user.credentials)+1)
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+
+	record := &models.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		Transports:      transports,
+		Flags:           uint8(credential.Flags.ProtocolValue()),
+		BackedUp:        credential.Flags.BackupState,
+		SignCount:       credential.Authenticator.SignCount,
+	}
+	if err := s.repo.CreateCredential(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *webAuthnService) BeginLogin(ctx context.Context, loginToken string) (*protocol.CredentialAssertion, uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.BeginLogin")
+	defer span.End()
+
+	// Passwordless: any passkey for this site, the authenticator tells us whose it is
+	if loginToken == "" {
+		assertion, data, err := s.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			return nil, uuid.Nil, err
+		}
+		sessionID, err := s.saveSession(ctx, nil, models.WebAuthnCeremonyLogin, data)
+		return assertion, sessionID, err
+	}
+
+	// Second factor: only the passkeys of the user who already passed the first one
+	tokenDoc, err := s.tokenService.VerifyToken(ctx, loginToken, models.TokenTypePasskeyLogin)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidPasskeySession
+	}
+	userID, err := uuid.Parse(tokenDoc.UserID)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidPasskeySession
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	assertion, data, err := s.webAuthn.BeginLogin(user)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	sessionID, err := s.saveSession(ctx, &userID, models.WebAuthnCeremonyLogin, data)
+	return assertion, sessionID, err
+}
+
+func (s *webAuthnService) FinishLogin(ctx context.Context, sessionID uuid.UUID, body io.Reader, client ClientInfo) (*models.User, map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.FinishLogin")
+	defer span.End()
+
+	session, data, err := s.takeSession(ctx, sessionID, models.WebAuthnCeremonyLogin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		return nil, nil, ErrPasskeyVerification
+	}
+
+	var user *webAuthnUser
+	var credential *webauthn.Credential
+	if session.UserID != nil {
+		if user, err = s.loadUser(ctx, *session.UserID); err != nil {
+			return nil, nil, ErrPasskeyVerification
+		}
+		credential, err = s.webAuthn.ValidateLogin(user, *data, parsed)
+	} else {
+		credential, err = s.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			userID, err := uuid.FromBytes(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			user, err = s.loadUser(ctx, userID)
+			return user, err
+		}, *data, parsed)
+	}
+	if err != nil {
+		return nil, nil, ErrPasskeyVerification
+	}
+
+	record := user.credential(credential.ID)
+	if record == nil {
+		return nil, nil, ErrPasskeyVerification
+	}
+
+	// Sign counters only move forward; a stale one means two copies of the key are in use
+	if credential.Authenticator.CloneWarning || record.CloneWarning {
+		record.CloneWarning = true
+		if err := s.repo.UpdateCredential(ctx, record); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrPasskeyCloned
+	}
+
+	now := time.Now()
+	record.SignCount = credential.Authenticator.SignCount
+	record.BackedUp = credential.Flags.BackupState
+	record.LastUsedAt = &now
+	if err := s.repo.UpdateCredential(ctx, record); err != nil {
+		return nil, nil, err
+	}
+
+	// The second factor is done, pending passkey steps of this user are no longer needed
+	if session.UserID != nil {
+		if err := s.tokenRepo.DeleteByUserIDAndType(ctx, user.ID.String(), models.TokenTypePasskeyLogin); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tokens, err := startSession(ctx, s.userRepo, s.tokenService, s.events, user.User, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user.User, tokens, nil
+}
+
+func (s *webAuthnService) ListCredentials(ctx context.Context, userID uuid.UUID) ([]models.WebAuthnCredential, error) {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.ListCredentials")
+	defer span.End()
+
+	return s.repo.FindCredentialsByUserID(ctx, userID)
+}
+
+func (s *webAuthnService) RenameCredential(ctx context.Context, userID, id uuid.UUID, name string) (*models.WebAuthnCredential, error) {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.RenameCredential")
+	defer span.End()
+
+	credential, err := s.repo.FindCredentialByIDAndUserID(ctx, id, userID)
+	if err != nil {
+		return nil, ErrPasskeyNotFound
+	}
+
+	credential.Name = name
+	if err := s.repo.UpdateCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (s *webAuthnService) DeleteCredential(ctx context.Context, userID, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.DeleteCredential")
+	defer span.End()
+
+	credential, err := s.repo.FindCredentialByIDAndUserID(ctx, id, userID)
+	if err != nil {
+		return ErrPasskeyNotFound
+	}
+	if err := s.repo.DeleteCredential(ctx, credential); err != nil {
+		return err
+	}
+
+	// Without passkeys left the second factor can't be satisfied, so it is switched off
+	remaining, err := s.repo.CountCredentialsByUserID(ctx, userID)
+	if err != nil || remaining > 0 {
+		return err
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.PasskeyRequired {
+		user.PasskeyRequired = false
+		return s.userRepo.Update(ctx, user)
+	}
+	return nil
+}
+
+func (s *webAuthnService) SetPasskeyRequired(ctx context.Context, userID uuid.UUID, required bool) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "WebAuthnService.SetPasskeyRequired")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if required {
+		count, err := s.repo.CountCredentialsByUserID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrNoPasskeys
+		}
+	}
+
+	user.PasskeyRequired = required
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *webAuthnService) loadUser(ctx context.Context, userID uuid.UUID) (*webAuthnUser, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	credentials, err := s.repo.FindCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &webAuthnUser{User: user, credentials: credentials}, nil
+}
+
+func (s *webAuthnService) saveSession(ctx context.Context, userID *uuid.UUID, ceremony string, data *webauthn.SessionData) (uuid.UUID, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	session := &models.WebAuthnSession{
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Data:      encoded,
+		ExpiresAt: time.Now().Add(webAuthnSessionTTL),
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return uuid.Nil, err
+	}
+	return session.ID, nil
+}
+
+func (s *webAuthnService) takeSession(ctx context.Context, id uuid.UUID, ceremony string) (*models.WebAuthnSession, *webauthn.SessionData, error) {
+	session, err := s.repo.TakeSession(ctx, id, ceremony)
+	if err != nil {
+		return nil, nil, ErrInvalidPasskeySession
+	}
+
+	var data webauthn.SessionData
+	if err := json.Unmarshal(session.Data, &data); err != nil {
+		return nil, nil, err
+	}
+	return session, &data, nil
+}
+
+// webAuthnUser adapts a user and their stored passkeys to webauthn.User
+type webAuthnUser struct {
+	*models.User
+	credentials []models.WebAuthnCredential
+}
+
+// WebAuthnID is the user handle stored on the authenticator: the raw 16 bytes of the user's UUID
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.ID[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.Name
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, len(c.Transports))
+		for j, t := range c.Transports {
+			transports[j] = protocol.AuthenticatorTransport(t)
+		}
+
+		credentials[i] = webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(c.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       c.AAGUID,
+				SignCount:    c.SignCount,
+				CloneWarning: c.CloneWarning,
+			},
+		}
+	}
+	return credentials
+}
+
+// credential returns the stored record for a credential ID
+func (u *webAuthnUser) credential(id []byte) *models.WebAuthnCredential {
+	for i := range u.credentials {
+		if string(u.credentials[i].CredentialID) == string(id) {
+			return &u.credentials[i]
+		}
+	}
+	return nil
+}
\ No newline at end of file
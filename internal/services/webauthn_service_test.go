@@ -0,0 +1,489 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"starter-kit-fullstack-gonethttp-template/config"
+	"starter-kit-fullstack-gonethttp-template/internal/models"
+	"starter-kit-fullstack-gonethttp-template/internal/repository"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+)
+
+const testOrigin = "https://app.example.com"
+
+// Authenticator data flags, see https://www.w3.org/TR/webauthn-3/#authdata-flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// softAuthenticator is a passkey in software: a P-256 key that answers the ceremonies the
+// way a browser and platform authenticator would, with "none" attestation
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &softAuthenticator{key: key, credentialID: id}
+}
+
+// create answers BeginRegistration with the body FinishRegistration expects
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation) []byte {
+	t.Helper()
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+
+	clientData := clientDataJSON(t, "webauthn.create", creation.Response.Challenge)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encode public key: %v", err)
+	}
+
+	var attested bytes.Buffer
+	attested.Write(make([]byte, 16)) // AAGUID, all zero for software authenticators
+	binary.Write(&attested, binary.BigEndian, uint16(len(a.credentialID)))
+	attested.Write(a.credentialID)
+	attested.Write(publicKey)
+
+	authData := a.authData(creation.Response.RelyingParty.ID, flagUserPresent|flagUserVerified|flagAttestedData, attested.Bytes())
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatalf("encode attestation: %v", err)
+	}
+
+	return credentialJSON(t, a.credentialID, map[string]interface{}{
+		"clientDataJSON":    b64(clientData),
+		"attestationObject": b64(attestation),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers BeginLogin with the body FinishLogin expects, counting the signature
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion) []byte {
+	t.Helper()
+	a.signCount++
+	return a.sign(t, assertion, a.signCount)
+}
+
+// sign answers BeginLogin with the given signature counter
+func (a *softAuthenticator) sign(t *testing.T, assertion *protocol.CredentialAssertion, signCount uint32) []byte {
+	t.Helper()
+	clientData := clientDataJSON(t, "webauthn.get", assertion.Response.Challenge)
+
+	saved := a.signCount
+	a.signCount = signCount
+	authData := a.authData(assertion.Response.RelyingPartyID, flagUserPresent|flagUserVerified, nil)
+	a.signCount = saved
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return credentialJSON(t, a.credentialID, map[string]interface{}{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) authData(rpID string, flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	var data bytes.Buffer
+	data.Write(rpIDHash[:])
+	data.WriteByte(flags)
+	binary.Write(&data, binary.BigEndian, a.signCount)
+	data.Write(attested)
+	return data.Bytes()
+}
+
+func clientDataJSON(t *testing.T, ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"type":      ceremony,
+		"challenge": challenge.String(),
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatalf("encode client data: %v", err)
+	}
+	return data
+}
+
+func credentialJSON(t *testing.T, id []byte, response map[string]interface{}) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       b64(id),
+		"rawId":    b64(id),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatalf("encode credential: %v", err)
+	}
+	return body
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+type webAuthnTest struct {
+	service  WebAuthnService
+	auth     AuthService
+	users    repository.UserRepository
+	passkeys repository.WebAuthnRepository
+}
+
+func newWebAuthnTest(t *testing.T) *webAuthnTest {
+	t.Helper()
+	db := newTestDB(t, &models.User{}, &models.Token{}, &models.WebAuthnCredential{}, &models.WebAuthnSession{})
+
+	cfg := &config.Config{}
+	cfg.App.Name = "Starter Kit"
+	cfg.JWT.Secret = "test-secret"
+	cfg.JWT.AccessExpirationMinutes = 30
+	cfg.JWT.RefreshExpirationDays = 30
+	cfg.WebAuthn.RPID = "app.example.com"
+	cfg.WebAuthn.Origins = []string{testOrigin}
+
+	users := repository.NewUserRepository(db)
+	tokens := repository.NewTokenRepository(db)
+	passkeys := repository.NewWebAuthnRepository(db)
+	tokenService := NewTokenService(tokens, cfg)
+	events := NewEventBus()
+
+	service, err := NewWebAuthnService(passkeys, users, tokens, tokenService, events, cfg)
+	if err != nil {
+		t.Fatalf("new webauthn service: %v", err)
+	}
+	return &webAuthnTest{
+		service:  service,
+		auth:     NewAuthService(users, tokens, tokenService, nil, events, cfg),
+		users:    users,
+		passkeys: passkeys,
+	}
+}
+
+func (wt *webAuthnTest) createUser(t *testing.T, email string) *models.User {
+	t.Helper()
+	user := &models.User{Name: "Jane", Email: email, Password: "password1", Role: models.RoleUser}
+	if err := wt.users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// register runs the whole registration ceremony for a new passkey of user
+func (wt *webAuthnTest) register(t *testing.T, user *models.User, a *softAuthenticator) *models.WebAuthnCredential {
+	t.Helper()
+	ctx := context.Background()
+	creation, sessionID, err := wt.service.BeginRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	credential, err := wt.service.FinishRegistration(ctx, user.ID, sessionID, "", bytes.NewReader(a.create(t, creation)))
+	if err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+	return credential
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	user := wt.createUser(t, "jane@example.com")
+	a := newSoftAuthenticator(t)
+
+	creation, sessionID, err := wt.service.BeginRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	if got := creation.Response.AuthenticatorSelection.ResidentKey; got != protocol.ResidentKeyRequirementRequired {
+		t.Errorf("resident key requirement is %q, passkeys must be discoverable", got)
+	}
+	if !bytes.Equal(creation.Response.User.ID.(protocol.URLEncodedBase64), user.ID[:]) {
+		t.Error("user handle is not the user's UUID")
+	}
+
+	body := a.create(t, creation)
+	credential, err := wt.service.FinishRegistration(ctx, user.ID, sessionID, "", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("finish registration: %v", err)
+	}
+	if !bytes.Equal(credential.CredentialID, a.credentialID) {
+		t.Error("stored credential ID differs from the authenticator's")
+	}
+	if credential.Name != "Passkey 1" {
+		t.Errorf("name = %q, want the default %q", credential.Name, "Passkey 1")
+	}
+
+	stored, err := wt.service.ListCredentials(ctx, user.ID)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("list credentials: %d, %v", len(stored), err)
+	}
+
+	// The session is spent by the first finish
+	if _, err := wt.service.FinishRegistration(ctx, user.ID, sessionID, "", bytes.NewReader(body)); !errors.Is(err, ErrInvalidPasskeySession) {
+		t.Errorf("finishing twice: got %v, want ErrInvalidPasskeySession", err)
+	}
+
+	// A registered passkey is excluded, so the browser won't create a second one on it
+	creation, _, err = wt.service.BeginRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	if len(creation.Response.CredentialExcludeList) != 1 {
+		t.Errorf("exclude list has %d credentials, want 1", len(creation.Response.CredentialExcludeList))
+	}
+}
+
+func TestWebAuthnRegistrationRejectsOtherUsersSession(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	jane := wt.createUser(t, "jane@example.com")
+	mallory := wt.createUser(t, "mallory@example.com")
+
+	creation, sessionID, err := wt.service.BeginRegistration(ctx, jane.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	body := newSoftAuthenticator(t).create(t, creation)
+	if _, err := wt.service.FinishRegistration(ctx, mallory.ID, sessionID, "", bytes.NewReader(body)); !errors.Is(err, ErrInvalidPasskeySession) {
+		t.Errorf("got %v, want ErrInvalidPasskeySession", err)
+	}
+}
+
+func TestWebAuthnRegistrationRejectsOtherSite(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	user := wt.createUser(t, "jane@example.com")
+
+	creation, sessionID, err := wt.service.BeginRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("begin registration: %v", err)
+	}
+	// A passkey created for another site carries that site's RP ID hash
+	creation.Response.RelyingParty.ID = "evil.example.net"
+	body := newSoftAuthenticator(t).create(t, creation)
+	if _, err := wt.service.FinishRegistration(ctx, user.ID, sessionID, "", bytes.NewReader(body)); !errors.Is(err, ErrPasskeyVerification) {
+		t.Errorf("got %v, want ErrPasskeyVerification", err)
+	}
+}
+
+func TestWebAuthnPasswordlessLogin(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	user := wt.createUser(t, "jane@example.com")
+	a := newSoftAuthenticator(t)
+	wt.register(t, user, a)
+
+	assertion, sessionID, err := wt.service.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	if len(assertion.Response.AllowedCredentials) != 0 {
+		t.Error("passwordless login names credentials, it should let the authenticator pick")
+	}
+
+	body := a.get(t, assertion)
+	got, tokens, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(body), ClientInfo{})
+	if err != nil {
+		t.Fatalf("finish login: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("signed in as %s, want %s", got.ID, user.ID)
+	}
+	if _, ok := tokens["access"]; !ok {
+		t.Error("no access token issued")
+	}
+
+	stored, err := wt.passkeys.FindCredentialsByUserID(ctx, user.ID)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("find credentials: %d, %v", len(stored), err)
+	}
+	if stored[0].SignCount != a.signCount || stored[0].LastUsedAt == nil {
+		t.Errorf("credential has signCount=%d lastUsedAt=%v, want %d and a time", stored[0].SignCount, stored[0].LastUsedAt, a.signCount)
+	}
+
+	// A replayed response finds its session spent
+	if _, _, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(body), ClientInfo{}); !errors.Is(err, ErrInvalidPasskeySession) {
+		t.Errorf("replay: got %v, want ErrInvalidPasskeySession", err)
+	}
+}
+
+func TestWebAuthnLoginRejectsBadSignature(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	user := wt.createUser(t, "jane@example.com")
+	a := newSoftAuthenticator(t)
+	wt.register(t, user, a)
+
+	assertion, sessionID, err := wt.service.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	// Same credential ID, different key
+	impostor := newSoftAuthenticator(t)
+	impostor.credentialID = a.credentialID
+	impostor.userHandle = a.userHandle
+	body := impostor.get(t, assertion)
+	if _, _, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(body), ClientInfo{}); !errors.Is(err, ErrPasskeyVerification) {
+		t.Errorf("got %v, want ErrPasskeyVerification", err)
+	}
+}
+
+func TestWebAuthnLoginDetectsClonedPasskey(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	user := wt.createUser(t, "jane@example.com")
+	a := newSoftAuthenticator(t)
+	wt.register(t, user, a)
+
+	for i := 0; i < 2; i++ {
+		assertion, sessionID, err := wt.service.BeginLogin(ctx, "")
+		if err != nil {
+			t.Fatalf("begin login: %v", err)
+		}
+		if _, _, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(a.get(t, assertion)), ClientInfo{}); err != nil {
+			t.Fatalf("finish login %d: %v", i, err)
+		}
+	}
+
+	// A copy of the key still counts from where it was copied
+	assertion, sessionID, err := wt.service.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	body := a.sign(t, assertion, 1)
+	if _, _, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(body), ClientInfo{}); !errors.Is(err, ErrPasskeyCloned) {
+		t.Fatalf("got %v, want ErrPasskeyCloned", err)
+	}
+
+	// The passkey stays blocked, even for the original
+	assertion, sessionID, err = wt.service.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	if _, _, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(a.get(t, assertion)), ClientInfo{}); !errors.Is(err, ErrPasskeyCloned) {
+		t.Errorf("after the clone warning: got %v, want ErrPasskeyCloned", err)
+	}
+}
+
+func TestWebAuthnSecondFactor(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	user := wt.createUser(t, "jane@example.com")
+	a := newSoftAuthenticator(t)
+	credential := wt.register(t, user, a)
+	if _, err := wt.service.SetPasskeyRequired(ctx, user.ID, true); err != nil {
+		t.Fatalf("require passkey: %v", err)
+	}
+
+	// The password alone only earns the login token
+	_, _, err := wt.auth.Login(ctx, user.Email, "password1", ClientInfo{})
+	var required *PasskeyRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("password login: got %v, want PasskeyRequiredError", err)
+	}
+
+	assertion, sessionID, err := wt.service.BeginLogin(ctx, required.Token)
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	allowed := assertion.Response.AllowedCredentials
+	if len(allowed) != 1 || !bytes.Equal(allowed[0].CredentialID, credential.CredentialID) {
+		t.Errorf("allowed credentials %v, want only the user's passkey", allowed)
+	}
+
+	got, tokens, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(a.get(t, assertion)), ClientInfo{})
+	if err != nil {
+		t.Fatalf("finish login: %v", err)
+	}
+	if got.ID != user.ID || tokens == nil {
+		t.Fatalf("signed in as %s with tokens %v", got.ID, tokens)
+	}
+
+	// Signing in used the login token up
+	if _, _, err := wt.service.BeginLogin(ctx, required.Token); !errors.Is(err, ErrInvalidPasskeySession) {
+		t.Errorf("reusing the login token: got %v, want ErrInvalidPasskeySession", err)
+	}
+}
+
+func TestWebAuthnSecondFactorOnlyAcceptsTheUsersPasskey(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	ctx := context.Background()
+	jane := wt.createUser(t, "jane@example.com")
+	mallory := wt.createUser(t, "mallory@example.com")
+	wt.register(t, jane, newSoftAuthenticator(t))
+	mallorysKey := newSoftAuthenticator(t)
+	wt.register(t, mallory, mallorysKey)
+	if _, err := wt.service.SetPasskeyRequired(ctx, jane.ID, true); err != nil {
+		t.Fatalf("require passkey: %v", err)
+	}
+
+	_, _, err := wt.auth.Login(ctx, jane.Email, "password1", ClientInfo{})
+	var required *PasskeyRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("password login: got %v, want PasskeyRequiredError", err)
+	}
+
+	assertion, sessionID, err := wt.service.BeginLogin(ctx, required.Token)
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	body := mallorysKey.get(t, assertion)
+	if _, _, err := wt.service.FinishLogin(ctx, sessionID, bytes.NewReader(body), ClientInfo{}); !errors.Is(err, ErrPasskeyVerification) {
+		t.Errorf("got %v, want ErrPasskeyVerification", err)
+	}
+}
+
+func TestWebAuthnBeginLoginRejectsInvalidToken(t *testing.T) {
+	wt := newWebAuthnTest(t)
+	if _, _, err := wt.service.BeginLogin(context.Background(), "not-a-token"); !errors.Is(err, ErrInvalidPasskeySession) {
+		t.Errorf("got %v, want ErrInvalidPasskeySession", err)
+	}
+	if _, _, err := wt.service.FinishLogin(context.Background(), uuid.New(), bytes.NewReader(nil), ClientInfo{}); !errors.Is(err, ErrInvalidPasskeySession) {
+		t.Errorf("unknown session: got %v, want ErrInvalidPasskeySession", err)
+	}
+}
\ No newline at end of file
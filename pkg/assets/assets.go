@@ -0,0 +1,204 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// hashLen is how many hex characters of the SHA-256 go into a fingerprint
+const hashLen = 8
+
+// compressible lists the extensions worth storing gzip and brotli copies of
+var compressible = map[string]bool{
+	".css": true, ".js": true, ".mjs": true, ".map": true, ".json": true,
+	".svg": true, ".txt": true, ".html": true, ".xml": true,
+}
+
+type file struct {
+	body   []byte
+	gzip   []byte // nil when compressing does not pay off
+	brotli []byte
+	hash   string
+}
+
+// Server serves static files with fingerprinted URLs: Path("js/app.js") gives
+// "/assets/js/app.1a2b3c4d.js", which is cached by browsers forever because its name
+// changes with its content. Plain names still work but are revalidated on each use.
+type Server struct {
+	fsys   fs.FS
+	prefix string
+	reload bool
+	files  map[string]*file
+}
+
+// New reads, fingerprints and compresses every file in fsys. prefix is the URL the
+// server is mounted under, e.g. "/assets/". With reload, files are read again on every
+// use instead, so edits show up in development.
+func New(fsys fs.FS, prefix string, reload bool) (*Server, error) {
+	s := &Server{fsys: fsys, prefix: prefix, reload: reload, files: map[string]*file{}}
+	if reload {
+		return s, nil
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := s.read(name, true)
+		if err != nil {
+			return err
+		}
+		s.files[name] = f
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load assets: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Server) read(name string, compress bool) (*file, error) {
+	body, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	f := &file{body: body, hash: hex.EncodeToString(sum[:])[:hashLen]}
+
+	if compress && compressible[path.Ext(name)] {
+		var gz, br bytes.Buffer
+		gw, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+		gw.Write(body)
+		gw.Close()
+		bw := brotli.NewWriterLevel(&br, brotli.BestCompression)
+		bw.Write(body)
+		bw.Close()
+
+		if gz.Len() < len(body) {
+			f.gzip = gz.Bytes()
+		}
+		if br.Len() < len(body) {
+			f.brotli = br.Bytes()
+		}
+	}
+	return f, nil
+}
+
+func (s *Server) lookup(name string) *file {
+	if !s.reload {
+		return s.files[name]
+	}
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+	f, err := s.read(name, false)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// Path returns the fingerprinted URL of a file, or its plain URL if there is no such file
+func (s *Server) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	f := s.lookup(name)
+	if f == nil {
+		return s.prefix + name
+	}
+	ext := path.Ext(name)
+	return s.prefix + strings.TrimSuffix(name, ext) + "." + f.hash + ext
+}
+
+// ServeHTTP serves the file named by the path below the prefix. Mount it with
+// http.StripPrefix. Directories are not listed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	f := s.lookup(name)
+	immutable := false
+	if f == nil {
+		// "js/app.1a2b3c4d.js": an outdated fingerprint still gets the current file, but
+		// only the current one may be cached for good
+		var hash string
+		name, hash = splitHash(name)
+		if f = s.lookup(name); f == nil {
+			http.NotFound(w, r)
+			return
+		}
+		immutable = hash == f.hash
+	}
+
+	h := w.Header()
+	if immutable {
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+
+	body, etag := f.body, f.hash
+	if f.gzip != nil || f.brotli != nil {
+		h.Add("Vary", "Accept-Encoding")
+		accept := r.Header.Get("Accept-Encoding")
+		switch {
+		case f.brotli != nil && accepts(accept, "br"):
+			body, etag = f.brotli, f.hash+"-br"
+			h.Set("Content-Encoding", "br")
+		case f.gzip != nil && accepts(accept, "gzip"):
+			body, etag = f.gzip, f.hash+"-gzip"
+			h.Set("Content-Encoding", "gzip")
+		}
+	}
+	h.Set("ETag", strconv.Quote(etag))
+
+	// ServeContent answers If-None-Match with 304 and handles ranges
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(body))
+}
+
+// splitHash turns "js/app.1a2b3c4d.js" into "js/app.js" and "1a2b3c4d"
+func splitHash(name string) (string, string) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	dot := strings.LastIndexByte(stem, '.')
+	if dot < 0 || len(stem)-dot-1 != hashLen {
+		return name, ""
+	}
+	return stem[:dot] + ext, stem[dot+1:]
+}
+
+// accepts reports whether an Accept-Encoding header allows coding. An explicit entry
+// beats "*", and q=0 means "not this one".
+func accepts(header, coding string) bool {
+	star := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		allowed := true
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				allowed = false
+			}
+		}
+		switch {
+		case strings.EqualFold(name, coding):
+			return allowed
+		case name == "*":
+			star = allowed
+		}
+	}
+	return star
+}
\ No newline at end of file
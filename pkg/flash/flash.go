@@ -0,0 +1,161 @@
+package flash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Level picks how a message is shown, the values are Bootstrap alert variants
+type Level string
+
+const (
+	Success Level = "success"
+	Info    Level = "info"
+	Warning Level = "warning"
+	Error   Level = "danger"
+)
+
+// Message is shown once, on the next page the user sees
+type Message struct {
+	Level Level  `json:"l"`
+	Text  string `json:"t"`
+}
+
+// State is what a request leaves for the next one: messages, and the form it posted so
+// the page it redirects to can fill the fields in again
+type State struct {
+	Messages []Message         `json:"m,omitempty"`
+	Input    map[string]string `json:"i,omitempty"`
+}
+
+const cookieName = "flash"
+
+// maxCookieSize keeps the cookie below the 4 KB browsers store, the input is dropped
+// when it would not fit
+const maxCookieSize = 3800
+
+var key []byte
+
+// Init sets the key messages are signed with, so nobody can plant text on a page by
+// crafting the cookie
+func Init(secret string) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("flash"))
+	key = mac.Sum(nil)
+}
+
+// Add queues a message for the next page, typically right before a redirect. text is
+// shown as is, translate it first.
+func Add(w http.ResponseWriter, r *http.Request, level Level, text string) {
+	state := pending(w, r)
+	state.Messages = append(state.Messages, Message{Level: level, Text: text})
+	save(w, state)
+}
+
+// KeepInput carries the posted form over to the next page, see Input
+func KeepInput(w http.ResponseWriter, r *http.Request) {
+	state := pending(w, r)
+	state.Input = Input(r)
+	save(w, state)
+}
+
+// Input returns the posted form fields, the first value of each. Passwords and tokens
+// are left out, they are never written back into a page.
+func Input(r *http.Request) map[string]string {
+	if err := r.ParseForm(); err != nil || len(r.PostForm) == 0 {
+		return nil
+	}
+	input := make(map[string]string, len(r.PostForm))
+	for name, values := range r.PostForm {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "password") || strings.Contains(lower, "token") || len(values) == 0 {
+			continue
+		}
+		input[name] = values[0]
+	}
+	return input
+}
+
+// Pop returns what the previous request queued and clears it. Messages added earlier
+// in this response are included.
+func Pop(w http.ResponseWriter, r *http.Request) State {
+	state := pending(w, r)
+	if _, err := r.Cookie(cookieName); err == nil {
+		http.SetCookie(w, &http.Cookie{Name: cookieName, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	}
+	return state
+}
+
+func save(w http.ResponseWriter, state State) {
+	payload, _ := json.Marshal(state)
+	value := sign(payload)
+	if len(value) > maxCookieSize && state.Input != nil {
+		state.Input = nil
+		payload, _ = json.Marshal(state)
+		value = sign(payload)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// pending returns what the request arrived with, or what an earlier call of this
+// response queued already, which replaces it
+func pending(w http.ResponseWriter, r *http.Request) State {
+	header := w.Header()
+	for i, line := range header.Values("Set-Cookie") {
+		if value, ok := strings.CutPrefix(line, cookieName+"="); ok {
+			value, _, _ = strings.Cut(value, ";")
+			cookies := header["Set-Cookie"]
+			header["Set-Cookie"] = append(cookies[:i:i], cookies[i+1:]...)
+			return decode(value)
+		}
+	}
+
+	if cookie, err := r.Cookie(cookieName); err == nil {
+		return decode(cookie.Value)
+	}
+	return State{}
+}
+
+func sign(payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// decode verifies a cookie value, anything tampered with or malformed yields nothing
+func decode(value string) State {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return State{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return State{}
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return State{}
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return State{}
+	}
+
+	var state State
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return State{}
+	}
+	return state
+}
\ No newline at end of file
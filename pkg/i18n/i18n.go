@@ -0,0 +1,256 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/validation"
+)
+
+// Keys are the English text itself, so a string without a translation shows up in
+// English and the default locale needs no catalog. Values may use {name} placeholders,
+// filled from the key/value pairs passed to T.
+var (
+	mu            sync.RWMutex
+	defaultLocale = "en"
+	catalogs      = map[string]map[string]string{}
+	locales       = []string{"en"}
+	matcher       = language.NewMatcher([]language.Tag{language.English})
+)
+
+type ctxKey string
+
+const localeCtxKey ctxKey = "locale"
+
+// validationPrefix marks catalog entries that translate validation rules, e.g.
+// "validation.required". They are handed to the validation package.
+const validationPrefix = "validation."
+
+// Load reads one catalog per locale from the root of fsys (web/locales), named after the
+// locale: id.json, pt-BR.toml, ... Nested objects are flattened with dots.
+func Load(fsys fs.FS, defaultLoc string) error {
+	files, err := fs.Glob(fsys, "*")
+	if err != nil {
+		return err
+	}
+
+	loaded := map[string]map[string]string{}
+	for _, file := range files {
+		ext := path.Ext(file)
+		if ext != ".json" && ext != ".toml" {
+			continue
+		}
+		tag, err := language.Parse(strings.TrimSuffix(file, ext))
+		if err != nil {
+			return fmt.Errorf("catalog %s: invalid locale: %w", file, err)
+		}
+		locale := tag.String()
+
+		messages, err := readCatalog(fsys, file)
+		if err != nil {
+			return fmt.Errorf("catalog %s: %w", file, err)
+		}
+		if loaded[locale] == nil {
+			loaded[locale] = map[string]string{}
+		}
+		for key, msg := range messages {
+			loaded[locale][key] = msg
+		}
+	}
+
+	defaultLoc = normalize(defaultLoc)
+	available := []string{defaultLoc}
+	for locale := range loaded {
+		if locale != defaultLoc {
+			available = append(available, locale)
+		}
+	}
+	sort.Strings(available[1:])
+
+	// The matcher answers with its first tag when nothing fits, so the default goes first
+	tags := make([]language.Tag, len(available))
+	for i, locale := range available {
+		tags[i] = language.Make(locale)
+	}
+
+	for locale, messages := range loaded {
+		rules := map[string]string{}
+		for key, msg := range messages {
+			if rule, ok := strings.CutPrefix(key, validationPrefix); ok {
+				rules[rule] = msg
+			}
+		}
+		if len(rules) > 0 {
+			validation.RegisterMessages(locale, rules)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLocale = defaultLoc
+	catalogs = loaded
+	locales = available
+	matcher = language.NewMatcher(tags)
+	return nil
+}
+
+func readCatalog(fsys fs.FS, file string) (map[string]string, error) {
+	raw, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	if path.Ext(file) == ".toml" {
+		err = toml.Unmarshal(raw, &tree)
+	} else {
+		err = json.Unmarshal(raw, &tree)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	messages := map[string]string{}
+	if err := flatten("", tree, messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, out map[string]string) error {
+	for key, value := range tree {
+		switch v := value.(type) {
+		case string:
+			out[prefix+key] = v
+		case map[string]interface{}:
+			if err := flatten(prefix+key+".", v, out); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s%s: messages must be strings, got %T", prefix, key, value)
+		}
+	}
+	return nil
+}
+
+// normalize turns "pt_br" and "PT-br" into "pt-BR", the form catalogs are stored under
+func normalize(locale string) string {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return locale
+	}
+	return tag.String()
+}
+
+// DefaultLocale is the locale used when nothing else matches
+func DefaultLocale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLocale
+}
+
+// Locales lists the supported locales, the default first
+func Locales() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]string(nil), locales...)
+}
+
+// Name is the language's name in itself, e.g. "Indonesia" for "id"
+func Name(locale string) string {
+	if name := display.Self.Name(language.Make(locale)); name != "" {
+		return name
+	}
+	return locale
+}
+
+// Supported reports whether locale is one of Locales, written the same way ("pt-BR")
+func Supported(locale string) bool {
+	for _, l := range Locales() {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate picks the supported locale that fits best, trying each preference in turn:
+// a locale ("id"), or an Accept-Language header ("id-ID,id;q=0.9,en;q=0.8").
+// Empty and unparsable preferences are skipped, the default locale is the last resort.
+func Negotiate(preferences ...string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, pref := range preferences {
+		if pref == "" {
+			continue
+		}
+		tags, _, err := language.ParseAcceptLanguage(pref)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		if _, index, confidence := matcher.Match(tags...); confidence != language.No {
+			return locales[index]
+		}
+	}
+	return defaultLocale
+}
+
+// WithLocale sets the locale of everything localized with ctx: T, validation messages
+// and the emails a request triggers
+func WithLocale(ctx context.Context, locale string) context.Context {
+	ctx = context.WithValue(ctx, localeCtxKey, locale)
+	return validation.WithLocale(ctx, locale)
+}
+
+// Locale returns the locale set with WithLocale, or the default locale
+func Locale(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeCtxKey).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale()
+}
+
+// T translates key into the locale of ctx, see Translate
+func T(ctx context.Context, key string, args ...interface{}) string {
+	return Translate(Locale(ctx), key, args...)
+}
+
+// Translate looks key up in locale, its base language ("pt" for "pt-BR") and the default
+// locale, falling back to the key itself. args are name/value pairs:
+// Translate("id", "Hello, {name}", "name", user.Name).
+func Translate(locale, key string, args ...interface{}) string {
+	msg := lookup(normalize(locale), key)
+	if len(args) < 2 {
+		return msg
+	}
+
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+fmt.Sprint(args[i])+"}", fmt.Sprint(args[i+1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+func lookup(locale, key string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	base, _, _ := strings.Cut(locale, "-")
+	for _, l := range []string{locale, base, defaultLocale} {
+		if msg, ok := catalogs[l][key]; ok {
+			return msg
+		}
+	}
+	return key
+}
\ No newline at end of file
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/telemetry"
+)
+
+type ctxKey string
+
+const scopeCtxKey ctxKey = "log_scope"
+
+// scope holds request values that are only known deeper in the middleware chain
+// (the user is authenticated after the request log is set up), shared by the whole request
+type scope struct {
+	mu     sync.RWMutex
+	userID string
+}
+
+// WithScope prepares ctx for WithUserID, so values set later also show up in records
+// logged by outer middleware with the original context
+func WithScope(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(scopeCtxKey).(*scope); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, scopeCtxKey, &scope{})
+}
+
+// WithUserID attaches the authenticated user to every record logged with ctx
+func WithUserID(ctx context.Context, userID string) context.Context {
+	ctx = WithScope(ctx)
+	s := ctx.Value(scopeCtxKey).(*scope)
+	s.mu.Lock()
+	s.userID = userID
+	s.mu.Unlock()
+	return ctx
+}
+
+// UserID returns the ID set by WithUserID
+func UserID(ctx context.Context) string {
+	s, ok := ctx.Value(scopeCtxKey).(*scope)
+	if !ok {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userID
+}
+
+// ContextHandler adds request_id, user_id, trace_id and span_id from the record's context,
+// so anything logged with slog.*Context can be matched to its request and trace
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps next
+func NewContextHandler(next slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: next}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := telemetry.RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if id := UserID(ctx); id != "" {
+		record.AddAttrs(slog.String("user_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
\ No newline at end of file
@@ -1,20 +1,63 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var Log *slog.Logger
 
-// InitLogger initializes the global logger
-func InitLogger(env string) {
+// Options configures the global logger
+type Options struct {
+	Format     string // text or json
+	Level      string // debug, info, warn or error
+	File       string // Also write to this file, rotated by size (empty = stdout only)
+	MaxSizeMB  int    // Rotate the file once it reaches this size
+	MaxBackups int    // Rotated files to keep (0 = all)
+	MaxAgeDays int    // Days to keep rotated files (0 = forever)
+}
+
+// InitLogger initializes the global logger and makes it the slog default,
+// which also routes the standard log package through it
+func InitLogger(opts Options) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", opts.Level, err)
+	}
+
+	var out io.Writer = os.Stdout
+	if opts.File != "" {
+		out = io.MultiWriter(os.Stdout, &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+		})
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
 	var handler slog.Handler
-	if env == "development" {
-		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
-	} else {
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})
+	switch strings.ToLower(opts.Format) {
+	case "json":
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	case "text":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	default:
+		return fmt.Errorf("invalid log format %q (use text or json)", opts.Format)
 	}
-	Log = slog.New(handler)
+
+	Log = slog.New(NewContextHandler(handler))
 	slog.SetDefault(Log)
+	return nil
+}
+
+// Fatal logs at error level and exits, for startup failures
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
\ No newline at end of file
@@ -0,0 +1,154 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Content is a rendered email with both the HTML and plain-text alternatives
+type Content struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Renderer renders email templates from a file system.
+//
+// Layout:
+//
+//	layouts/base.html, base.txt   shared layouts
+//	<locale>/<name>.html          HTML body ("content" block)
+//	<locale>/<name>.txt           text body ("content" block) and "subject" block
+type Renderer struct {
+	fsys          fs.FS
+	defaultLocale string
+}
+
+// NewRenderer creates a Renderer reading from fsys (web/templates/emails)
+func NewRenderer(fsys fs.FS, defaultLocale string) *Renderer {
+	return &Renderer{fsys: fsys, defaultLocale: defaultLocale}
+}
+
+// funcs are shared by the HTML and text templates
+var funcs = map[string]interface{}{
+	"year": func() int {
+		return time.Now().Year()
+	},
+	"date": func(t time.Time) string {
+		return t.Format("January 2, 2006 15:04 MST")
+	},
+}
+
+// Render renders the named template for a locale, falling back to the
+// language without region ("id-ID" -> "id") and then to the default locale.
+func (r *Renderer) Render(name, locale string, data map[string]interface{}) (*Content, error) {
+	dir, err := r.resolve(name, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy so the caller's map is not mutated when we add the subject
+	vars := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	textTmpl, err := texttemplate.New("base.txt").Funcs(funcs).ParseFS(r.fsys,
+		"layouts/base.txt",
+		path.Join(dir, name+".txt"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("parse text email %q: %w", name, err)
+	}
+
+	var subject bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", vars); err != nil {
+		return nil, fmt.Errorf("render subject of %q: %w", name, err)
+	}
+	vars["Subject"] = strings.TrimSpace(subject.String())
+
+	var text bytes.Buffer
+	if err := textTmpl.Execute(&text, vars); err != nil {
+		return nil, fmt.Errorf("render text email %q: %w", name, err)
+	}
+
+	htmlTmpl, err := htmltemplate.New("base.html").Funcs(funcs).ParseFS(r.fsys,
+		"layouts/base.html",
+		path.Join(dir, name+".html"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("parse html email %q: %w", name, err)
+	}
+
+	var html bytes.Buffer
+	if err := htmlTmpl.Execute(&html, vars); err != nil {
+		return nil, fmt.Errorf("render html email %q: %w", name, err)
+	}
+
+	return &Content{
+		Subject: vars["Subject"].(string),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+// Templates lists the template names available in the default locale
+func (r *Renderer) Templates() ([]string, error) {
+	files, err := fs.Glob(r.fsys, path.Join(r.defaultLocale, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(path.Base(f), ".txt"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Locales lists the locale directories that contain templates
+func (r *Renderer) Locales() ([]string, error) {
+	entries, err := fs.ReadDir(r.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var locales []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "layouts" {
+			locales = append(locales, e.Name())
+		}
+	}
+	return locales, nil
+}
+
+// resolve finds the locale directory holding the template
+func (r *Renderer) resolve(name, locale string) (string, error) {
+	if strings.ContainsAny(name, `/\.`) {
+		return "", fmt.Errorf("invalid email template name %q", name)
+	}
+
+	candidates := []string{locale}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, lang)
+	}
+	candidates = append(candidates, r.defaultLocale)
+
+	for _, l := range candidates {
+		if l == "" || strings.ContainsAny(l, `/\.`) {
+			continue
+		}
+		if _, err := fs.Stat(r.fsys, path.Join(l, name+".txt")); err == nil {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("email template %q not found", name)
+}
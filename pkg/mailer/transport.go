@@ -0,0 +1,139 @@
+package mailer
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EmailTransport delivers a fully built message
+type EmailTransport interface {
+	Send(msg *Message) error
+}
+
+// LogTransport prints emails to the console instead of sending them. Bodies carry
+// reset, verification and sign-in links, so they are only printed with ShowBody
+// (development); otherwise the recipient and subject are logged.
+type LogTransport struct {
+	ShowBody bool
+}
+
+func (t LogTransport) Send(msg *Message) error {
+	to := make([]string, len(msg.To))
+	for i, addr := range msg.To {
+		to[i] = addr.Address
+	}
+	if t.ShowBody {
+		slog.Info("Mock email", "to", strings.Join(to, ", "), "subject", msg.Subject, "text", msg.Text)
+		return nil
+	}
+	slog.Info("Mock email", "to", strings.Join(to, ", "), "subject", msg.Subject)
+	return nil
+}
+
+// FileTransport writes every email as an .eml file that mail clients can open
+type FileTransport struct {
+	Dir string
+}
+
+func (t *FileTransport) Send(msg *Message) error {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), randomHex(4))
+	return os.WriteFile(filepath.Join(t.Dir, name), raw, 0o600)
+}
+
+// CapturedMessage is an email kept by MemoryTransport
+type CapturedMessage struct {
+	ID         int       `json:"id"`
+	From       string    `json:"from"`
+	To         []string  `json:"to"`
+	Subject    string    `json:"subject"`
+	Text       string    `json:"text"`
+	HTML       string    `json:"html"`
+	Raw        []byte    `json:"-"`
+	CapturedAt time.Time `json:"capturedAt"`
+}
+
+// MemoryTransport keeps the most recent emails in memory for the dev inbox and tests
+type MemoryTransport struct {
+	mu       sync.RWMutex
+	messages []CapturedMessage
+	nextID   int
+	limit    int
+}
+
+// NewMemoryTransport keeps at most limit messages, dropping the oldest first
+func NewMemoryTransport(limit int) *MemoryTransport {
+	return &MemoryTransport{limit: limit, nextID: 1}
+}
+
+func (t *MemoryTransport) Send(msg *Message) error {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages = append(t.messages, CapturedMessage{
+		ID:         t.nextID,
+		From:       msg.From.String(),
+		To:         msg.Recipients(),
+		Subject:    msg.Subject,
+		Text:       msg.Text,
+		HTML:       msg.HTML,
+		Raw:        raw,
+		CapturedAt: time.Now(),
+	})
+	t.nextID++
+
+	if t.limit > 0 && len(t.messages) > t.limit {
+		t.messages = t.messages[len(t.messages)-t.limit:]
+	}
+	return nil
+}
+
+// Messages returns the captured emails, newest first
+func (t *MemoryTransport) Messages() []CapturedMessage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make([]CapturedMessage, len(t.messages))
+	for i, m := range t.messages {
+		out[len(out)-1-i] = m
+	}
+	return out
+}
+
+// Get returns a captured email by ID
+func (t *MemoryTransport) Get(id int) (CapturedMessage, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, m := range t.messages {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return CapturedMessage{}, false
+}
+
+// Clear empties the inbox
+func (t *MemoryTransport) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
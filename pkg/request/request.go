@@ -0,0 +1,138 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/validation"
+)
+
+// DefaultMaxBodyBytes applies to routes that did not set their own limit with LimitBody
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+type ctxKey string
+
+const maxBodyCtxKey ctxKey = "max_body_bytes"
+
+// Error is a request body the client has to fix. Fields is only set when the
+// body decoded fine but failed validation.
+type Error struct {
+	Status  int
+	Code    string // Machine readable, e.g. "invalid_json"
+	Message string
+	Fields  map[string]string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrEmptyBody is returned for a request without a body, handlers with an optional body check for it
+var ErrEmptyBody = &Error{Status: http.StatusBadRequest, Code: "empty_body", Message: "request body must not be empty"}
+
+// LimitBody caps the body of r at maxBytes, reading past it fails with a 413
+func LimitBody(w http.ResponseWriter, r *http.Request, maxBytes int64) *http.Request {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	return r.WithContext(context.WithValue(r.Context(), maxBodyCtxKey, maxBytes))
+}
+
+// DecodeJSON strictly decodes a single JSON object from the body into dst and validates it
+// with validation.Struct. The body must be application/json, unknown fields are rejected.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return ErrEmptyBody
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return &Error{
+			Status:  http.StatusUnsupportedMediaType,
+			Code:    "unsupported_media_type",
+			Message: "Content-Type must be application/json",
+		}
+	}
+
+	maxBytes, limited := r.Context().Value(maxBodyCtxKey).(int64)
+	if !limited {
+		maxBytes = DefaultMaxBodyBytes
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	}
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err, maxBytes)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return invalidJSON("request body must contain a single JSON object")
+	}
+
+	if errs := validation.Struct(r.Context(), dst); errs != nil {
+		return &Error{
+			Status:  http.StatusBadRequest,
+			Code:    "validation_failed",
+			Message: "the request has invalid fields",
+			Fields:  errs,
+		}
+	}
+	return nil
+}
+
+// decodeError turns what encoding/json reports into a message that points at the problem
+func decodeError(err error, maxBytes int64) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrEmptyBody
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return invalidJSON("request body contains incomplete JSON")
+	case errors.As(err, &syntaxErr):
+		return invalidJSON(fmt.Sprintf("request body contains malformed JSON at position %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return invalidJSON(fmt.Sprintf("request body must be a JSON object, not %s", typeErr.Value))
+		}
+		return invalidJSON(fmt.Sprintf("%s must be %s, not %s", typeErr.Field, jsonType(typeErr.Type.Kind().String()), typeErr.Value))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return &Error{Status: http.StatusBadRequest, Code: "unknown_field", Message: "request body contains unknown field " + field}
+	case errors.As(err, &maxBytesErr):
+		return &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    "body_too_large",
+			Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytes),
+		}
+	default:
+		// e.g. a malformed UUID, reported by the field's own UnmarshalJSON
+		return invalidJSON("request body is invalid: " + err.Error())
+	}
+}
+
+func invalidJSON(message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: "invalid_json", Message: message}
+}
+
+// jsonType names a Go kind the way a JSON client knows it
+func jsonType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "string":
+		return "a string"
+	case kind == "slice", kind == "array":
+		return "an array"
+	default:
+		return "an object"
+	}
+}
\ No newline at end of file
@@ -3,6 +3,9 @@ package response
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
 )
 
 type APIResponse struct {
@@ -20,14 +23,55 @@ func JSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-// Error writes a standardized error response
-func Error(w http.ResponseWriter, status int, message interface{}) {
-	JSON(w, status, APIResponse{
-		Code:    status,
-		Message: message,
+// Problem is an RFC 7807 problem details document, the body of every API error
+type Problem struct {
+	Type   string            `json:"type"`
+	Title  string            `json:"title"`
+	Status int               `json:"status"`
+	Detail string            `json:"detail,omitempty"`
+	Code   string            `json:"code"`             // Machine readable, e.g. "email_taken"
+	Errors map[string]string `json:"errors,omitempty"` // Per-field messages of a failed validation
+}
+
+// WriteProblem writes p as application/problem+json, filling in the defaults of RFC 7807
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Code == "" {
+		p.Code = statusCode(p.Status)
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error writes a problem whose code is derived from the status, e.g. 404 becomes "not_found".
+// detail is translated into the locale of the request.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, Problem{Status: status, Detail: i18n.T(r.Context(), detail)})
+}
+
+// ValidationError writes the field errors returned by validation.Struct, which are
+// already in the locale of the request
+func ValidationError(w http.ResponseWriter, r *http.Request, errs map[string]string) {
+	WriteProblem(w, Problem{
+		Status: http.StatusBadRequest,
+		Detail: i18n.T(r.Context(), "the request has invalid fields"),
+		Code:   "validation_failed",
+		Errors: errs,
 	})
 }
 
+// statusCode turns the status text into a code, "Too Many Requests" becomes "too_many_requests"
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
 // Success writes a standardized success response (optional wrapper)
 func Success(w http.ResponseWriter, status int, data interface{}) {
 	// If the data is already a map/struct, just return it directly like the PHP controller
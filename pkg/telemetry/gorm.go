@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "telemetry:span"
+
+// GormTracing is a GORM plugin that wraps statements in client spans. Only statements
+// run with db.WithContext(ctx) inside a trace (a request, an email delivery) get one.
+type GormTracing struct{}
+
+func (GormTracing) Name() string {
+	return "telemetry"
+}
+
+func (p GormTracing) Initialize(db *gorm.DB) error {
+	tracer := Tracer("gorm")
+	startSpan := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			// Background queries (migrations, outbox polling) would each become a trace of their own
+			if !trace.SpanContextFromContext(tx.Statement.Context).IsValid() {
+				return
+			}
+			ctx, span := tracer.Start(tx.Statement.Context, "db."+operation, trace.WithSpanKind(trace.SpanKindClient))
+			tx.Statement.Context = ctx
+			tx.InstanceSet(gormSpanKey, span)
+		}
+	}
+
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("telemetry:before_create", startSpan("create")),
+		cb.Create().After("gorm:create").Register("telemetry:after_create", p.endSpan),
+		cb.Query().Before("gorm:query").Register("telemetry:before_query", startSpan("query")),
+		cb.Query().After("gorm:query").Register("telemetry:after_query", p.endSpan),
+		cb.Update().Before("gorm:update").Register("telemetry:before_update", startSpan("update")),
+		cb.Update().After("gorm:update").Register("telemetry:after_update", p.endSpan),
+		cb.Delete().Before("gorm:delete").Register("telemetry:before_delete", startSpan("delete")),
+		cb.Delete().After("gorm:delete").Register("telemetry:after_delete", p.endSpan),
+		cb.Row().Before("gorm:row").Register("telemetry:before_row", startSpan("row")),
+		cb.Row().After("gorm:row").Register("telemetry:after_row", p.endSpan),
+		cb.Raw().Before("gorm:raw").Register("telemetry:before_raw", startSpan("raw")),
+		cb.Raw().After("gorm:raw").Register("telemetry:after_raw", p.endSpan),
+	)
+}
+
+func (GormTracing) endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.system", tx.Dialector.Name()),
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.String("db.sql.table", tx.Statement.Table),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
\ No newline at end of file
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configures tracing. Without Enabled, trace and span IDs are still
+// generated (and honored from incoming traceparent headers) but nothing is exported.
+type Options struct {
+	ServiceName string
+	Enabled     bool
+	Endpoint    string  // OTLP/HTTP collector, host:port
+	Insecure    bool    // Plain HTTP to the collector
+	SampleRatio float64 // Share of new traces that are recorded and exported
+}
+
+type ctxKey string
+
+const requestIDCtxKey ctxKey = "request_id"
+
+// Init installs the global tracer provider and W3C trace context propagator.
+// The returned function flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	sampler := sdktrace.ParentBased(sdktrace.NeverSample())
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", opts.ServiceName))),
+	}
+
+	if opts.Enabled {
+		exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+		if err != nil {
+			return nil, err
+		}
+		sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(5*time.Second)))
+	}
+
+	provider := sdktrace.NewTracerProvider(append(providerOpts, sdktrace.WithSampler(sampler))...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns a named tracer from the global provider. It is safe to call before Init,
+// spans are forwarded to the real provider once it is installed.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// WithRequestID stores the request ID so logs and queries made with ctx carry it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, id)
+}
+
+// RequestID returns the ID stored by WithRequestID
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDCtxKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// TraceParent serializes the span context of ctx as a W3C traceparent value
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// SpanContextFromTraceParent parses a value produced by TraceParent
+func SpanContextFromTraceParent(traceParent string) trace.SpanContext {
+	carrier := propagation.MapCarrier{"traceparent": traceParent}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), carrier)
+	return trace.SpanContextFromContext(ctx)
+}
\ No newline at end of file
@@ -1,53 +0,0 @@
-package utils
-
-import (
-	"fmt"
-	"reflect"
-	"strings"
-
-	"github.com/go-playground/validator/v10"
-)
-
-var validate *validator.Validate
-
-func init() {
-	validate = validator.New()
-	// Register function to get json tag name instead of struct field name
-	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
-		if name == "-" {
-			return ""
-		}
-		return name
-	})
-}
-
-// ValidateStruct validates a struct and returns a map of errors if any
-func ValidateStruct(s interface{}) map[string]string {
-	err := validate.Struct(s)
-	if err == nil {
-		return nil
-	}
-
-	errors := make(map[string]string)
-	for _, err := range err.(validator.ValidationErrors) {
-		field := err.Field()
-		tag := err.Tag()
-		param := err.Param()
-
-		switch tag {
-		case "required":
-			errors[field] = fmt.Sprintf("%s is required", field)
-		case "email":
-			errors[field] = fmt.Sprintf("%s must be a valid email", field)
-		case "min":
-			errors[field] = fmt.Sprintf("%s must be at least %s characters", field)
-		case "oneof":
-			errors[field] = fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(param, " ", ", "))
-		default:
-			errors[field] = fmt.Sprintf("%s failed validation on tag %s", field, tag)
-		}
-	}
-
-	return errors
-}
\ No newline at end of file
@@ -0,0 +1,180 @@
+package validation
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultLocale has a message for every built-in rule, other locales fall back to it
+const DefaultLocale = "en"
+
+type ctxKey string
+
+const localeCtxKey ctxKey = "validation_locale"
+
+// Message templates use {field} and {param}. Rules whose meaning depends on the field's
+// kind (min, max, len, ...) are looked up as "tag.string", "tag.items" or "tag.number"
+// before falling back to the plain tag, "tag.items.one" and friends cover a param of 1.
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]map[string]string{
+		DefaultLocale: {
+			"required":         "{field} is required",
+			"required_if":      "{field} is required",
+			"required_with":    "{field} is required",
+			"required_without": "{field} is required",
+			"email":            "{field} must be a valid email address",
+			"url":              "{field} must be a valid URL",
+			"http_url":         "{field} must be a valid http(s) URL",
+			"uuid":             "{field} must be a valid UUID",
+			"oneof":            "{field} must be one of: {param}",
+			"eqfield":          "{field} must match {param}",
+			"nefield":          "{field} must be different from {param}",
+			"alpha":            "{field} may only contain letters",
+			"alphanum":         "{field} may only contain letters and numbers",
+			"numeric":          "{field} must be a number",
+			"number":           "{field} must be a number",
+			"boolean":          "{field} must be true or false",
+			"e164":             "{field} must be a phone number in international format, e.g. +14155550123",
+			"datetime":         "{field} must be a date in the format {param}",
+			"ip":               "{field} must be a valid IP address",
+			"hostname":         "{field} must be a valid hostname",
+			"lowercase":        "{field} must be lowercase",
+			"uppercase":        "{field} must be uppercase",
+			"contains":         "{field} must contain '{param}'",
+			"excludes":         "{field} must not contain '{param}'",
+			"startswith":       "{field} must start with '{param}'",
+			"endswith":         "{field} must end with '{param}'",
+			"unique":           "{field} must not contain duplicates",
+			"password":         "{field} must be at least {param} characters and contain a letter and a number",
+			"unique_email":     "{field} is already registered",
+			"locale":           "{field} must be a supported language",
+
+			"min.string":     "{field} must be at least {param} characters",
+			"min.string.one": "{field} must be at least one character",
+			"min.items":      "{field} must contain at least {param} items",
+			"min.items.one":  "{field} must contain at least one item",
+			"min.number":     "{field} must be at least {param}",
+			"max.string":     "{field} must be at most {param} characters",
+			"max.string.one": "{field} must be at most one character",
+			"max.items":      "{field} must contain at most {param} items",
+			"max.items.one":  "{field} must contain at most one item",
+			"max.number":     "{field} must be at most {param}",
+			"len.string":     "{field} must be exactly {param} characters",
+			"len.string.one": "{field} must be exactly one character",
+			"len.items":      "{field} must contain exactly {param} items",
+			"len.items.one":  "{field} must contain exactly one item",
+			"len.number":     "{field} must be {param}",
+			"gt.string":      "{field} must be longer than {param} characters",
+			"gt.items":       "{field} must contain more than {param} items",
+			"gt.number":      "{field} must be greater than {param}",
+			"gte.string":     "{field} must be at least {param} characters",
+			"gte.items":      "{field} must contain at least {param} items",
+			"gte.items.one":  "{field} must contain at least one item",
+			"gte.number":     "{field} must be at least {param}",
+			"lt.string":      "{field} must be shorter than {param} characters",
+			"lt.items":       "{field} must contain fewer than {param} items",
+			"lt.number":      "{field} must be less than {param}",
+			"lte.string":     "{field} must be at most {param} characters",
+			"lte.items":      "{field} must contain at most {param} items",
+			"lte.items.one":  "{field} must contain at most one item",
+			"lte.number":     "{field} must be at most {param}",
+
+			"default": "{field} is invalid",
+		},
+	}
+)
+
+// RegisterMessages adds or overrides message templates for locale, e.g. a translation
+// or the message of a rule added with Register
+func RegisterMessages(locale string, messages map[string]string) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+
+	locale = strings.ToLower(locale)
+	if catalogs[locale] == nil {
+		catalogs[locale] = make(map[string]string, len(messages))
+	}
+	for key, msg := range messages {
+		catalogs[locale][key] = msg
+	}
+}
+
+// WithLocale makes Struct report messages in locale, e.g. "de" or "pt-BR"
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeCtxKey, locale)
+}
+
+// Locale returns the locale set with WithLocale, or DefaultLocale
+func Locale(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeCtxKey).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
+
+func message(locale string, fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "oneof":
+		param = strings.ReplaceAll(param, " ", ", ")
+	case "password":
+		// The rule has no param, its length comes from the same constant it checks
+		param = strconv.Itoa(MinPasswordLength)
+	case "eqfield", "nefield":
+		// The param is the Go field name, the client knows it by its json name
+		param = strings.ToLower(param[:1]) + param[1:]
+	}
+
+	keys := []string{fe.Tag()}
+	if kind := kindSuffix(fe.Kind()); kind != "" {
+		keys = append([]string{fe.Tag() + "." + kind}, keys...)
+		if param == "1" {
+			keys = append([]string{fe.Tag() + "." + kind + ".one"}, keys...)
+		}
+	}
+	msg := lookup(locale, keys)
+	if msg == "" {
+		// The English message of the rule beats a translated "is invalid"
+		msg = lookup(locale, []string{"default"})
+	}
+	return strings.NewReplacer("{field}", fe.Field(), "{param}", param).Replace(msg)
+}
+
+// lookup finds the first key in locale, then in its base language ("pt" for "pt-BR"),
+// then in DefaultLocale
+func lookup(locale string, keys []string) string {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	locale = strings.ToLower(locale)
+	base, _, _ := strings.Cut(locale, "-")
+	for _, l := range []string{locale, base, DefaultLocale} {
+		for _, key := range keys {
+			if msg, ok := catalogs[l][key]; ok {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+func kindSuffix(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return ""
+	}
+}
\ No newline at end of file
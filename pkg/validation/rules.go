@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"context"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// MinPasswordLength is the shortest password the password rule accepts
+const MinPasswordLength = 8
+
+var builtinRules = map[string]validator.FuncCtx{
+	"password": password,
+	"uuid":     validUUID,
+}
+
+// password requires MinPasswordLength characters with at least one letter and one digit
+func password(ctx context.Context, fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if utf8.RuneCountInString(value) < MinPasswordLength {
+		return false
+	}
+
+	var letter, digit bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return letter && digit
+}
+
+// validUUID replaces the built-in uuid rule so it also covers uuid.UUID fields,
+// where the zero UUID counts as invalid
+func validUUID(ctx context.Context, fl validator.FieldLevel) bool {
+	switch value := fl.Field().Interface().(type) {
+	case uuid.UUID:
+		return value != uuid.Nil
+	case string:
+		_, err := uuid.Parse(value)
+		return err == nil
+	default:
+		return false
+	}
+}
\ No newline at end of file
@@ -0,0 +1,86 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// embedded names an anonymous struct field in error namespaces. Like encoding/json,
+// its fields are addressed as if they were declared on the outer struct.
+const embedded = "~embedded"
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by their json name, the way the client sent them
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" && fld.Anonymous {
+			return embedded
+		}
+		return name
+	})
+
+	for tag, fn := range builtinRules {
+		if err := v.RegisterValidationCtx(tag, fn); err != nil {
+			panic(err)
+		}
+	}
+	return v
+}
+
+// Register adds a custom rule usable in validate tags. fn gets the context passed to
+// Struct, so rules that hit the database run within the request.
+func Register(tag string, fn validator.FuncCtx) error {
+	return validate.RegisterValidationCtx(tag, fn)
+}
+
+// Errors maps a field path to its message. Paths follow the JSON body: "email",
+// "address.city", "scopes[1]".
+type Errors map[string]string
+
+// Struct validates s and returns nil when it is valid. Messages are in the locale
+// set on ctx with WithLocale.
+func Struct(ctx context.Context, s interface{}) Errors {
+	err := validate.StructCtx(ctx, s)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		// Not a struct, a programming error rather than bad input
+		panic(err)
+	}
+
+	locale := Locale(ctx)
+	errs := make(Errors, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		path := fieldPath(fe.Namespace())
+		if _, ok := errs[path]; !ok {
+			errs[path] = message(locale, fe)
+		}
+	}
+	return errs
+}
+
+// fieldPath drops the root type and embedded structs from a namespace
+// like "CreateUserRequest.~embedded.email"
+func fieldPath(namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+	path := segments[:0]
+	for _, segment := range segments {
+		if segment != embedded {
+			path = append(path, segment)
+		}
+	}
+	return strings.Join(path, ".")
+}
\ No newline at end of file
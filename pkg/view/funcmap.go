@@ -1,8 +1,16 @@
 package view
 
-import "html/template"
+import (
+	"html/template"
 
-// GetFuncMap returns global template functions
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
+	"starter-kit-fullstack-gonethttp-template/pkg/validation"
+)
+
+// GetFuncMap returns global template functions. t translates into the default locale
+// and old returns its fallback, Render swaps them for ones bound to the request:
+//
+//	{{ t "Welcome back, {name}" "name" .User.Name }}
 func GetFuncMap() template.FuncMap {
 	return template.FuncMap{
 		"add": func(a, b int) int {
@@ -11,5 +19,23 @@ func GetFuncMap() template.FuncMap {
 		"sub": func(a, b int) int {
 			return a - b
 		},
+		"t": func(key string, args ...interface{}) string {
+			return i18n.Translate(i18n.DefaultLocale(), key, args...)
+		},
+		"old": func(name string, fallback ...string) string {
+			return firstOf(fallback)
+		},
+		"languageName": i18n.Name,
+		// For password hints and minlength, so they follow the password rule
+		"minPasswordLength": func() int {
+			return validation.MinPasswordLength
+		},
+	}
+}
+
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
 	}
+	return values[0]
 }
\ No newline at end of file
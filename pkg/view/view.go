@@ -1,69 +1,257 @@
 package view
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
+	"io/fs"
+	"log/slog"
 	"net/http"
-	"path/filepath"
-	"sync"
+	"path"
+	"strconv"
+	"strings"
 
 	"starter-kit-fullstack-gonethttp-template/config"
 	"starter-kit-fullstack-gonethttp-template/internal/middleware"
+	"starter-kit-fullstack-gonethttp-template/pkg/assets"
+	"starter-kit-fullstack-gonethttp-template/pkg/flash"
+	"starter-kit-fullstack-gonethttp-template/pkg/i18n"
 )
 
 var (
-	baseDir = "web/templates"
-	cfg     *config.Config
-	once    sync.Once
+	cfg         *config.Config
+	templates   fs.FS
+	staticFiles *assets.Server
+	reload      bool
+
+	// Parsed once by Init and never executed themselves: every render works on a clone,
+	// with t bound to its request
+	pages      = map[string]*template.Template{} // "layout:view"
+	errorPages = map[int]*template.Template{}
 )
 
-// Init sets the configuration for views
-func Init(c *config.Config) {
-	once.Do(func() {
-		cfg = c
+// Init parses every page in fsys (web/templates) with every layout, and the error pages,
+// so a template syntax error stops the app at startup rather than failing a request.
+// In development the files are parsed again on each render, so edits show up on reload.
+// static backs the asset function.
+func Init(c *config.Config, fsys fs.FS, static *assets.Server) error {
+	cfg = c
+	templates = fsys
+	staticFiles = static
+	reload = c.App.Env == "development"
+
+	layouts, err := fs.Glob(fsys, "layouts/*.html")
+	if err != nil {
+		return err
+	}
+	views, err := listViews(fsys)
+	if err != nil {
+		return err
+	}
+
+	for _, layoutFile := range layouts {
+		layout := strings.TrimSuffix(path.Base(layoutFile), ".html")
+		for _, view := range views {
+			tmpl, err := parsePage(layout, view)
+			if err != nil {
+				return err
+			}
+			pages[layout+":"+view] = tmpl
+		}
+	}
+
+	errorFiles, err := fs.Glob(fsys, "errors/*.html")
+	if err != nil {
+		return err
+	}
+	for _, file := range errorFiles {
+		status, err := strconv.Atoi(strings.TrimSuffix(path.Base(file), ".html"))
+		if err != nil {
+			continue
+		}
+		tmpl, err := parseErrorPage(status)
+		if err != nil {
+			return err
+		}
+		errorPages[status] = tmpl
+	}
+	return nil
+}
+
+// listViews returns the pages ("auth/login") under fsys, everything but the layouts,
+// partials, error pages and emails
+func listViews(fsys fs.FS) ([]string, error) {
+	var views []string
+	err := fs.WalkDir(fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			switch file {
+			case "layouts", "partials", "errors", "emails":
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if path.Ext(file) == ".html" && strings.Contains(file, "/") {
+			views = append(views, strings.TrimSuffix(file, ".html"))
+		}
+		return nil
 	})
+	return views, err
+}
+
+// parsePage parses a view with its layout and all partials (always included for
+// simplicity in this starter kit)
+func parsePage(layout, view string) (*template.Template, error) {
+	partials, err := fs.Glob(templates, "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	files := append([]string{"layouts/" + layout + ".html", view + ".html"}, partials...)
+
+	tmpl, err := template.New(layout+".html").Funcs(funcs()).ParseFS(templates, files...)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s with layout %s: %w", view, layout, err)
+	}
+	return tmpl, nil
 }
 
-// Render renders a template with a layout
+func parseErrorPage(status int) (*template.Template, error) {
+	file := fmt.Sprintf("errors/%d.html", status)
+	tmpl, err := template.New(path.Base(file)).Funcs(funcs()).ParseFS(templates, file)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return tmpl, nil
+}
+
+// funcs is GetFuncMap plus the functions that need the configuration:
+//
+//	<script src="{{ asset "js/api-client.js" }}"></script>
+func funcs() template.FuncMap {
+	funcMap := GetFuncMap()
+	funcMap["asset"] = staticFiles.Path
+	funcMap["baseUrl"] = func(path string) string {
+		if path == "/" {
+			return cfg.App.URL
+		}
+		return fmt.Sprintf("%s/%s", cfg.App.URL, path)
+	}
+	return funcMap
+}
+
+// Render renders a template with a layout. Besides data the page gets the flash
+// messages queued for it as .Flashes (see partials/flash.html), and old, which fills a
+// field in with what was posted last: by a form that redirected here with
+// flash.KeepInput, or by this request when a failed post renders its form again.
+//
+//	<input name="email" value="{{ old "email" .User.Email }}">
 func Render(w http.ResponseWriter, r *http.Request, viewPath string, data map[string]interface{}, layout string) {
+	RenderStatus(w, r, http.StatusOK, viewPath, data, layout)
+}
+
+// RenderStatus is Render answering with status, e.g. a form shown again with its errors.
+// The header goes out after the page rendered, so popping the flash cookie still counts.
+func RenderStatus(w http.ResponseWriter, r *http.Request, status int, viewPath string, data map[string]interface{}, layout string) {
 	if data == nil {
 		data = make(map[string]interface{})
 	}
 
+	state := flash.Pop(w, r)
+	old := state.Input
+	if old == nil {
+		old = flash.Input(r)
+	}
+
 	// Add Global Data
 	data["AppURL"] = cfg.App.URL
 	data["AppName"] = cfg.App.Name
 	data["CSRFToken"] = middleware.GetCSRFToken(r) // Inject CSRF token
+	data["Locale"] = i18n.Locale(r.Context())
+	data["Locales"] = i18n.Locales()
+	data["Flashes"] = state.Messages
 
-	// Define standard functions for templates
-	funcMap := template.FuncMap{
-		"baseUrl": func(path string) string {
-			if path == "/" {
-				return cfg.App.URL
-			}
-			return fmt.Sprintf("%s/%s", cfg.App.URL, path)
-		},
+	tmpl, err := instance(r, old, pages[layout+":"+viewPath], func() (*template.Template, error) {
+		return parsePage(layout, viewPath)
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Template parse error", "view", viewPath, "error", err)
+		RenderError(w, r, http.StatusInternalServerError)
+		return
 	}
 
-	// Build path to files
-	// Layouts are in web/templates/layouts/
-	layoutFile := filepath.Join(baseDir, "layouts", layout+".html")
-	// Views are relative to web/templates/
-	viewFile := filepath.Join(baseDir, viewPath+".html")
-	
-	// Partials (Always include all partials for simplicity in this starter kit)
-	partials, _ := filepath.Glob(filepath.Join(baseDir, "partials", "*.html"))
+	// Execute into a buffer so a failing template never leaves half a page behind
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		slog.ErrorContext(r.Context(), "Template execute error", "view", viewPath, "error", err)
+		RenderError(w, r, http.StatusInternalServerError)
+		return
+	}
 
-	files := append([]string{layoutFile, viewFile}, partials...)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
 
-	tmpl, err := template.New(filepath.Base(layoutFile)).Funcs(funcMap).ParseFiles(files...)
+// RenderError renders the standalone page web/templates/errors/{status}.html,
+// falling back to plain text for statuses without a page
+func RenderError(w http.ResponseWriter, r *http.Request, status int) {
+	tmpl, err := instance(r, nil, errorPages[status], func() (*template.Template, error) {
+		return parseErrorPage(status)
+	})
 	if err != nil {
-		http.Error(w, "Template Parse Error: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, http.StatusText(status), status)
 		return
 	}
 
-	err = tmpl.Execute(w, data)
+	data := map[string]interface{}{
+		"AppURL":    cfg.App.URL,
+		"AppName":   cfg.App.Name,
+		"RequestID": middleware.GetRequestID(r),
+		"Locale":    i18n.Locale(r.Context()),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		slog.ErrorContext(r.Context(), "Error page execute error", "status", status, "error", err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// instance returns a template ready to execute for r: a clone of the cached one, or a
+// fresh parse in development. A page Init did not find ends up as a parse error.
+func instance(r *http.Request, old map[string]string, cached *template.Template, parse func() (*template.Template, error)) (*template.Template, error) {
+	var tmpl *template.Template
+	var err error
+	if reload || cached == nil {
+		tmpl, err = parse()
+	} else {
+		tmpl, err = cached.Clone()
+	}
 	if err != nil {
-		http.Error(w, "Template Execute Error: "+err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	return tmpl.Funcs(requestFuncs(r, old)), nil
+}
+
+// requestFuncs overrides t and old in GetFuncMap with ones bound to r: t translates into
+// its locale, old looks fields up in the input it carried
+func requestFuncs(r *http.Request, input map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			return i18n.T(r.Context(), key, args...)
+		},
+		"old": func(name string, fallback ...string) string {
+			if value, ok := input[name]; ok {
+				return value
+			}
+			return firstOf(fallback)
+		},
 	}
 }
\ No newline at end of file
@@ -6,106 +6,205 @@ const API = {
     // Base URL is injected via global variable or calculated
     baseUrl: window.APP_URL || window.location.origin,
 
-    // Helper to make authenticated requests
+    // Helper to make authenticated requests. The session cookies the server set at sign-in
+    // authenticate them, there are no tokens to handle here.
     async fetch(url, options = {}) {
         // Ensure URL is complete
         const fullUrl = url.startsWith('http') ? url : `${this.baseUrl}${url}`;
         
-        const token = localStorage.getItem('accessToken');
-        
-        // Get CSRF Token from Meta Tag (Injected by Go Template)
-        const csrfTokenMeta = document.querySelector('meta[name="csrf-token"]');
-        const csrfToken = csrfTokenMeta ? csrfTokenMeta.getAttribute('content') : '';
-        
         const headers = {
             'Content-Type': 'application/json',
             'Accept': 'application/json',
-            'X-CSRF-TOKEN': csrfToken, // Header for API middleware
+            'X-CSRF-TOKEN': this._csrfToken(), // Header for API middleware
             ...options.headers
         };
 
-        if (token) {
-            headers['Authorization'] = `Bearer ${token}`;
-        }
-
         const config = {
             ...options,
             headers
         };
 
-        // Handle CSRF for FormData (if body is not string)
-        if (options.body && typeof options.body === 'string') {
-            // If it's a string (JSON), we already sent header.
-            // If strict CSRF middleware checks body key, append it (usually header is enough for AJAX)
-        }
-
         const response = await fetch(fullUrl, config);
         
-        // Handle Token Expiry (401 Unauthorized)
-        if (response.status === 401) {
-            // Try to refresh token if we have a refresh token
-            // For now, simple logout logic
-            if (!window.location.pathname.includes('/login') && 
-                !window.location.pathname.includes('/register') &&
-                !window.location.pathname.includes('/forgot-password')) {
-                
-                // alert('Session expired. Please login again.'); // Optional UI feedback
-                API.logout();
-            }
+        // Handle an ended session (401 Unauthorized): sign in again and come back here.
+        // Auth pages answer their own 401s, a failed sign-in for one.
+        if (response.status === 401 && !this._onAuthPage()) {
+            const here = window.location.pathname + window.location.search;
+            window.location.href = `${this.baseUrl}/login?next=${encodeURIComponent(here)}`;
         }
 
         return response;
     },
 
-    saveTokens(tokens) {
-        if (tokens.access && tokens.access.token) {
-            localStorage.setItem('accessToken', tokens.access.token);
-        }
-        if (tokens.refresh && tokens.refresh.token) {
-            localStorage.setItem('refreshToken', tokens.refresh.token);
+    // CSRF Token from Meta Tag (Injected by Go Template)
+    _csrfToken() {
+        const csrfTokenMeta = document.querySelector('meta[name="csrf-token"]');
+        return csrfTokenMeta ? csrfTokenMeta.getAttribute('content') : '';
+    },
+
+    _onAuthPage() {
+        const authPaths = ['/login', '/register', '/forgot-password', '/reset-password', '/verify-email', '/accept-invite', '/secure-account', '/auth/magic'];
+        return authPaths.some(p => window.location.pathname.includes(p));
+    },
+
+    // Call after signing in through the API, whose response already set the session
+    // cookies: switches to the language of the user
+    signedIn(user) {
+        if (user) {
+            this.saveLocale(user.locale);
         }
     },
 
+    // The locale cookie picks the language of pages and API messages, '' follows the browser
+    saveLocale(locale) {
+        document.cookie = locale
+            ? `locale=${encodeURIComponent(locale)}; path=/; max-age=31536000; SameSite=Lax`
+            : 'locale=; path=/; max-age=0; SameSite=Lax';
+    },
+
+    // Signs out through the logout form, which revokes the session and clears its cookies
     logout() {
-        localStorage.removeItem('accessToken');
-        localStorage.removeItem('refreshToken');
-        window.location.href = `${this.baseUrl}/login`;
+        const form = document.createElement('form');
+        form.method = 'POST';
+        form.action = `${this.baseUrl}/logout`;
+        const csrf = document.createElement('input');
+        csrf.type = 'hidden';
+        csrf.name = 'csrf_token';
+        csrf.value = this._csrfToken();
+        form.append(csrf);
+        document.body.append(form);
+        form.submit();
     },
 
-    checkAuth() {
-        const token = localStorage.getItem('accessToken');
-        const path = window.location.pathname;
-        
-        // Public paths that don't require auth
-        const publicPaths = ['/login', '/register', '/forgot-password'];
-        const isPublic = publicPaths.some(p => path.includes(p));
+    // Where to go after signing in: back to the page that sent the visitor to the login
+    // page (?next=/users), or the dashboard
+    afterLoginUrl(next = new URLSearchParams(window.location.search).get('next')) {
+        const local = next && next.startsWith('/') && !next.startsWith('//');
+        return `${this.baseUrl}${local ? next : '/'}`;
+    },
 
-        if (!token && !isPublic) {
-            window.location.href = `${this.baseUrl}/login`;
-        }
-        
-        // If logged in and trying to access login page, redirect to dashboard
-        if (token && isPublic) {
-            window.location.href = `${this.baseUrl}/`;
+    // Readable text of an error response (application/problem+json); field errors win over the summary
+    errorMessage(problem) {
+        if (!problem) return '';
+        if (problem.errors) return Object.values(problem.errors).join('<br>');
+        return problem.detail || problem.title || '';
+    },
+
+    // Shows each field error of a validation problem below the form control named after its
+    // path ("scopes[1]" goes to name="scopes"); a group of checkboxes is marked with data-field.
+    // Returns the text of what could not be placed, for the form's alert, or '' when every
+    // error is next to its field.
+    showFieldErrors(form, problem) {
+        this.clearFieldErrors(form);
+        if (!problem || !problem.errors) return this.errorMessage(problem);
+
+        const unplaced = [];
+        for (const [path, msg] of Object.entries(problem.errors)) {
+            const input = this._fieldControl(form, path) || this._fieldControl(form, path.replace(/\[\d+\]$/, ''));
+            if (!input) {
+                unplaced.push(msg);
+                continue;
+            }
+            input.classList.add('is-invalid');
+            const feedback = document.createElement('div');
+            feedback.className = 'invalid-feedback d-block';
+            feedback.dataset.fieldError = path;
+            feedback.textContent = msg;
+            (input.closest('.form-check') || input).after(feedback);
         }
+        return unplaced.join('<br>');
+    },
+
+    _fieldControl(form, path) {
+        return form.querySelector(`[name="${path}"], [data-field="${path}"]`);
+    },
+
+    // Removes what showFieldErrors added, call it before submitting again
+    clearFieldErrors(form) {
+        form.querySelectorAll('.is-invalid').forEach(el => el.classList.remove('is-invalid'));
+        form.querySelectorAll('[data-field-error]').forEach(el => el.remove());
+    },
+
+    // --- Passkeys (WebAuthn) ---
+    // The server sends binary fields base64url encoded, the browser API works with ArrayBuffers
+
+    passkeysSupported() {
+        return !!window.PublicKeyCredential;
+    },
+
+    _toBuffer(value) {
+        const base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+        const binary = atob(base64.padEnd(base64.length + (4 - base64.length % 4) % 4, '='));
+        return Uint8Array.from(binary, c => c.charCodeAt(0)).buffer;
+    },
+
+    _toBase64(buffer) {
+        if (!buffer) return null;
+        const binary = String.fromCharCode(...new Uint8Array(buffer));
+        return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
     },
-    
-    // Helper to parse JWT payload
-    getUser() {
-        const token = localStorage.getItem('accessToken');
-        if(!token) return null;
-        try {
-            return JSON.parse(atob(token.split('.')[1]));
-        } catch (e) {
-            return null;
+
+    async _beginCeremony(url, body) {
+        const response = await this.fetch(url, { method: 'POST', body: JSON.stringify(body || {}) });
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(this.errorMessage(data) || 'Passkey request failed');
         }
-    }
-};
+        return data;
+    },
+
+    // Registers a passkey for the signed in user, resolves with the finish response
+    async registerPasskey(name = '') {
+        const begin = await this._beginCeremony('/v1/auth/webauthn/register/begin');
+        const publicKey = begin.options.publicKey;
+        publicKey.challenge = this._toBuffer(publicKey.challenge);
+        publicKey.user.id = this._toBuffer(publicKey.user.id);
+        (publicKey.excludeCredentials || []).forEach(c => c.id = this._toBuffer(c.id));
+
+        const credential = await navigator.credentials.create({ publicKey });
 
-// Run Auth Check on Load
-document.addEventListener('DOMContentLoaded', () => {
-    // Only run auth check if we are not on the Swagger UI
-    if (!window.location.pathname.includes('/swagger')) {
-        API.checkAuth();
+        return this.fetch(`/v1/auth/webauthn/register/finish?session=${begin.sessionId}&name=${encodeURIComponent(name)}`, {
+            method: 'POST',
+            body: JSON.stringify({
+                id: credential.id,
+                rawId: this._toBase64(credential.rawId),
+                type: credential.type,
+                authenticatorAttachment: credential.authenticatorAttachment,
+                clientExtensionResults: credential.getClientExtensionResults(),
+                response: {
+                    clientDataJSON: this._toBase64(credential.response.clientDataJSON),
+                    attestationObject: this._toBase64(credential.response.attestationObject),
+                    transports: credential.response.getTransports ? credential.response.getTransports() : []
+                }
+            })
+        });
+    },
+
+    // Signs in with a passkey. Pass the loginToken from a passkeyRequired response for the second factor,
+    // leave it empty for passwordless sign-in. Resolves with the finish response ({ user, tokens } on success).
+    async loginWithPasskey(loginToken = '') {
+        const begin = await this._beginCeremony('/v1/auth/webauthn/login/begin', loginToken ? { loginToken } : {});
+        const publicKey = begin.options.publicKey;
+        publicKey.challenge = this._toBuffer(publicKey.challenge);
+        (publicKey.allowCredentials || []).forEach(c => c.id = this._toBuffer(c.id));
+
+        const assertion = await navigator.credentials.get({ publicKey });
+
+        return this.fetch(`/v1/auth/webauthn/login/finish?session=${begin.sessionId}`, {
+            method: 'POST',
+            body: JSON.stringify({
+                id: assertion.id,
+                rawId: this._toBase64(assertion.rawId),
+                type: assertion.type,
+                authenticatorAttachment: assertion.authenticatorAttachment,
+                clientExtensionResults: assertion.getClientExtensionResults(),
+                response: {
+                    clientDataJSON: this._toBase64(assertion.response.clientDataJSON),
+                    authenticatorData: this._toBase64(assertion.response.authenticatorData),
+                    signature: this._toBase64(assertion.response.signature),
+                    userHandle: this._toBase64(assertion.response.userHandle)
+                }
+            })
+        });
     }
-});
\ No newline at end of file
+};
@@ -0,0 +1,23 @@
+// Package web holds the templates, static files and message catalogs, embedded so the
+// binary runs without a web/ directory next to it
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed locales static templates
+var embedded embed.FS
+
+// FS returns the web files rooted at web/. In development they are read from disk when
+// the app runs from the repository, so edits show up without a rebuild.
+func FS(env string) fs.FS {
+	if env == "development" {
+		if info, err := os.Stat("web/templates"); err == nil && info.IsDir() {
+			return os.DirFS("web")
+		}
+	}
+	return embedded
+}
\ No newline at end of file